@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/minefuto/tp/engine"
+	"github.com/rivo/tview"
+)
+
+// outputFormatFlag selects how the result is printed on exit: text prints
+// the output of the pipeline as is, json prints an acceptResult.
+var outputFormatFlag string
+
+// includeOutputFlag includes the output of the pipeline in the JSON result.
+var includeOutputFlag bool
+
+// acceptResult is the result of tp printed with --output-format json.
+type acceptResult struct {
+	Command    string   `json:"command"`
+	Stages     []string `json:"stages"`
+	Cursor     int      `json:"cursor"`
+	Action     string   `json:"action"`
+	File       string   `json:"file,omitempty"`
+	ExitStatus int      `json:"exit_status"`
+	DurationMs int64    `json:"duration_ms"`
+	Output     *string  `json:"output,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+func jsonOutput() bool {
+	return outputFormatFlag == "json"
+}
+
+// checkOutputFormat checks the value of --output-format.
+func checkOutputFormat() error {
+	switch outputFormatFlag {
+	case "text", "json":
+		return nil
+	}
+	return fmt.Errorf("unknown output format: %s (text or json)", outputFormatFlag)
+}
+
+// pipelineText returns the whole pipeline built so far, with the stages
+// after the cursor given by --cursor.
+func (t *tui) pipelineText() string {
+	return restoreNewlines(adjustPipe(t.cliPane.prompt) + t.cliPane.GetText() + t.cliPane.rest)
+}
+
+// splitStages splits text into the stages the same way as the command line.
+func splitStages(text string) []string {
+	return engine.Parse(text).Commands()
+}
+
+func newAcceptResult(action, text string, cursor int) *acceptResult {
+	return &acceptResult{
+		Command: text,
+		Stages:  splitStages(text),
+		Cursor:  cursor,
+		Action:  action,
+	}
+}
+
+// runPipeline runs the whole pipeline on the input with its output written
+// to stdout, and returns its exit status. The error of the pipeline itself
+// is reported on stderr by the shell, so it isn't returned.
+func runPipeline(text string, stdout io.Writer) (int, error) {
+	stdin, err := stdinData.reader()
+	if err != nil {
+		return 0, err
+	}
+	defer closeReader(stdin)
+	if inputStream != nil {
+		stdin = inputStream.reader()
+	}
+
+	e := shellExecutor()
+	r := e.Run(context.Background(), text, stdin, stdout, os.Stderr)
+	return r.ExitStatus, r.Err
+}
+
+// run runs the pipeline of r with its output written to w.
+func (r *acceptResult) run(w io.Writer) {
+	start := time.Now()
+	status, err := runPipeline(r.Command, w)
+	r.ExitStatus, r.DurationMs = status, time.Since(start).Milliseconds()
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// capture runs the pipeline of r with its output kept in r with
+// --include-output. Otherwise the output is discarded, as stdout is taken by
+// the JSON result.
+func (r *acceptResult) capture() []byte {
+	if !includeOutputFlag {
+		r.run(io.Discard)
+		return nil
+	}
+	b := new(bytes.Buffer)
+	r.run(b)
+	output := b.String()
+	r.Output = &output
+	return b.Bytes()
+}
+
+// stopPreview stops the previews and the application before the pipeline
+// runs on the terminal.
+func (t *tui) stopPreview() {
+	t.stdinPane.cancel()
+	t.stdoutPane.cancel()
+	t.Stop()
+}
+
+// accept stops the application, and starts the result of the action on the
+// current pipeline.
+func (t *tui) accept(action string) *acceptResult {
+	t.stopPreview()
+	t.result = newAcceptResult(action, t.pipelineText(), t.cliPane.commandCursor())
+	return t.result
+}
+
+// finish reports the error of the result on stderr, unless it's printed
+// with --output-format json.
+func (t *tui) finish() {
+	if t.result.Error != "" && !jsonOutput() {
+		fmt.Fprintln(os.Stderr, t.result.Error)
+	}
+}
+
+// acceptCancel records that tp was quit, keeping the initial command.
+func (t *tui) acceptCancel() {
+	t.result = newAcceptResult("cancel", initCommand, cursorFlag)
+	if commandFlag && !jsonOutput() {
+		printCommand(initCommand, cursorFlag)
+	}
+}
+
+// acceptRun runs the pipeline with its output on stdout, or prints only the
+// command with -c.
+func (t *tui) acceptRun() {
+	if commandFlag {
+		r := t.accept("command")
+		if !jsonOutput() {
+			printCommand(r.Command, r.Cursor)
+		}
+		return
+	}
+	r := t.accept("run")
+	if jsonOutput() {
+		r.capture()
+	} else {
+		r.run(os.Stdout)
+	}
+	t.finish()
+}
+
+// acceptFile writes the output of the pipeline to path.
+func (t *tui) acceptFile(path string) {
+	r := t.accept("file")
+	r.File = path
+	defer t.finish()
+	f, err := os.Create(path)
+	if err != nil {
+		r.Error = err.Error()
+		return
+	}
+	r.run(f)
+	if err := f.Close(); err != nil && r.Error == "" {
+		r.Error = err.Error()
+	}
+}
+
+// acceptClipboard copies the output of the pipeline to the clipboard.
+func (t *tui) acceptClipboard() {
+	r := t.accept("clipboard")
+	defer t.finish()
+	b := new(bytes.Buffer)
+	r.run(b)
+	if r.Error != "" {
+		return
+	}
+	if err := copyToClipboard(b.Bytes()); err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// acceptPrint prints the pipeline followed by its output.
+func (t *tui) acceptPrint() {
+	r := t.accept("print")
+	defer t.finish()
+	if jsonOutput() {
+		r.capture()
+		return
+	}
+	fmt.Println("$ " + r.Command)
+	r.run(os.Stdout)
+}
+
+// printResult prints the result with --output-format json.
+func printResult(r *acceptResult) error {
+	if !jsonOutput() || r == nil {
+		return nil
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(r)
+}
+
+// openWriteBar asks for the file to write the output of the pipeline to.
+func (t *tui) openWriteBar() {
+	t.writePath = ""
+	t.writeFocus = t.GetFocus()
+	t.writeBar.SetLabel("write to: ").SetText("")
+	t.root.AddItem(t.writeBar, 1, 0, false)
+	t.SetFocus(t.writeBar)
+}
+
+func (t *tui) closeWriteBar() {
+	t.root.RemoveItem(t.writeBar)
+	t.SetFocus(t.writeFocus)
+}
+
+// doneWriteBar writes the output to the file entered, after asking whether
+// to overwrite it if it exists.
+func (t *tui) doneWriteBar(key tcell.Key) {
+	if key != tcell.KeyEnter {
+		t.closeWriteBar()
+		return
+	}
+	text := t.writeBar.GetText()
+	if t.writePath != "" {
+		if !strings.EqualFold(text, "y") {
+			t.closeWriteBar()
+			return
+		}
+		t.acceptFile(t.writePath)
+		return
+	}
+
+	if text == "" {
+		return
+	}
+	if _, err := os.Stat(text); err == nil {
+		t.writePath = text
+		t.writeBar.SetLabel(tview.Escape(text) + " exists, overwrite? (y/n): ").SetText("")
+		return
+	}
+	t.acceptFile(text)
+}
+
+func newWriteBar() *tview.InputField {
+	w := tview.NewInputField()
+	w.SetFieldWidth(0)
+	return w
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestRunPipeline(t *testing.T) {
+	shell = "sh"
+	defer func() {
+		stdinData = storeBytes(nil)
+	}()
+	stdinData = storeBytes([]byte("b\na\nb\n"))
+
+	cases := []struct {
+		text   string
+		result string
+		status int
+	}{
+		{text: "sort | uniq", result: "a\nb\n", status: 0},
+		{text: "grep c", result: "", status: 1},
+		{text: "sort; exit 3", result: "a\nb\nb\n", status: 3},
+	}
+	for _, tc := range cases {
+		b := new(bytes.Buffer)
+		status, err := runPipeline(tc.text, b)
+		if err != nil || b.String() != tc.result || status != tc.status {
+			t.Errorf("\nresult:   %q %d %v\nexpected: %q %d", b.String(), status, err, tc.result, tc.status)
+		}
+	}
+}
+
+func TestSplitStages(t *testing.T) {
+	cases := []struct {
+		input  string
+		result []string
+	}{
+		{input: "ls", result: []string{"ls"}},
+		{input: "ls | grep a |wc -l", result: []string{"ls", "grep a", "wc -l"}},
+		{input: "ls |", result: []string{"ls", ""}},
+	}
+	for _, tc := range cases {
+		result, _ := json.Marshal(splitStages(tc.input))
+		expected, _ := json.Marshal(tc.result)
+		if !bytes.Equal(result, expected) {
+			t.Errorf("\nresult:   %s\nexpected: %s", result, expected)
+		}
+	}
+}
+
+func TestAcceptResult(t *testing.T) {
+	shell = "sh"
+	defer func() {
+		stdinData, includeOutputFlag = storeBytes(nil), false
+	}()
+	stdinData = storeBytes([]byte("b\na\n"))
+
+	r := newAcceptResult("run", "sort | head -1; exit 2", 14)
+	r.capture()
+	r.DurationMs = 0
+	result, _ := json.Marshal(r)
+	expected := `{"command":"sort | head -1; exit 2","stages":["sort","head -1; exit 2"],"cursor":14,"action":"run","exit_status":2,"duration_ms":0}`
+	if string(result) != expected {
+		t.Errorf("\nresult:   %s\nexpected: %s", result, expected)
+	}
+
+	includeOutputFlag = true
+	r = newAcceptResult("run", "sort | head -1", 14)
+	r.capture()
+	r.DurationMs = 0
+	result, _ = json.Marshal(r)
+	expected = `{"command":"sort | head -1","stages":["sort","head -1"],"cursor":14,"action":"run","exit_status":0,"duration_ms":0,"output":"a\n"}`
+	if string(result) != expected {
+		t.Errorf("\nresult:   %s\nexpected: %s", result, expected)
+	}
+
+	r = newAcceptResult("command", "ls", 2)
+	result, _ = json.Marshal(r)
+	expected = `{"command":"ls","stages":["ls"],"cursor":2,"action":"command","exit_status":0,"duration_ms":0}`
+	if string(result) != expected {
+		t.Errorf("\nresult:   %s\nexpected: %s", result, expected)
+	}
+}
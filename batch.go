@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/minefuto/tp/engine"
+)
+
+var (
+	// batchFlag runs the pipeline stage by stage without the UI.
+	batchFlag bool
+	// batchLines is the number of lines shown of the input and output of
+	// each stage.
+	batchLines int
+)
+
+// batchData is the input or an output of a stage in the batch report.
+type batchData struct {
+	Head  string `json:"head"`
+	Lines int64  `json:"lines"`
+	Bytes int64  `json:"bytes"`
+}
+
+// batchStage is the report of a stage.
+type batchStage struct {
+	Command    string    `json:"command"`
+	Input      batchData `json:"input"`
+	Output     batchData `json:"output"`
+	Stderr     batchData `json:"stderr"`
+	ExitStatus int       `json:"exit_status"`
+	DurationMs int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// lineCounter counts the bytes and lines written to it.
+type lineCounter struct {
+	lines int64
+	bytes int64
+	last  byte
+}
+
+func (c *lineCounter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		c.lines += int64(bytes.Count(p, []byte("\n")))
+		c.bytes += int64(len(p))
+		c.last = p[len(p)-1]
+	}
+	return len(p), nil
+}
+
+func (c *lineCounter) data(head []byte) batchData {
+	lines := c.lines
+	if c.bytes > 0 && c.last != '\n' {
+		lines++
+	}
+	return batchData{Head: string(headLines(head, batchLines)), Lines: lines, Bytes: c.bytes}
+}
+
+// stageWatcher follows the stages run by runStages.
+type stageWatcher interface {
+	// output returns a writer which the output of the i-th stage is also
+	// written to while it runs.
+	output(i int) io.Writer
+	// done is called with the report of the i-th stage when it ends.
+	done(i int, stage batchStage)
+}
+
+// runBatch runs the stages of text one by one on input, each on the output
+// of the previous one, through the same sandbox as the preview.
+func runBatch(ctx context.Context, text string, input *store) []batchStage {
+	return runStages(ctx, text, input, nil)
+}
+
+// runStages runs the stages of text like runBatch, notifying watch if it
+// isn't nil. It stops at a stage which can't be run, or when ctx is done.
+func runStages(ctx context.Context, text string, input *store, watch stageWatcher) []batchStage {
+	sink := &batchSink{watch: watch}
+	count := &lineCounter{}
+	if r, err := input.reader(); err == nil {
+		io.Copy(count, r)
+		closeReader(r)
+	}
+	sink.input = count.data(input.bytes())
+
+	e := shellExecutor()
+	e.RunPipeline(ctx, engine.Parse(text), storeBuffer{input}, sink)
+	return sink.stages
+}
+
+// batchSink makes the report of the stages run by the executor. Only the
+// head of the output of a stage is kept.
+type batchSink struct {
+	watch  stageWatcher
+	stages []batchStage
+	input  batchData
+
+	stdout, stderr           *store
+	stdoutCount, stderrCount *lineCounter
+}
+
+func (b *batchSink) Stdout(i int, stage engine.Stage) io.Writer {
+	b.stdout, b.stdoutCount = newStore(false), &lineCounter{}
+	w := io.MultiWriter(b.stdout, b.stdoutCount)
+	if b.watch != nil {
+		w = io.MultiWriter(w, b.watch.output(i))
+	}
+	return w
+}
+
+func (b *batchSink) Stderr(i int, stage engine.Stage) io.Writer {
+	b.stderr, b.stderrCount = newStore(false), &lineCounter{}
+	return io.MultiWriter(b.stderr, b.stderrCount)
+}
+
+func (b *batchSink) Done(i int, r engine.StageResult) {
+	stage := batchStage{
+		Command:    r.Stage.Command,
+		Input:      b.input,
+		Output:     b.stdoutCount.data(b.stdout.bytes()),
+		Stderr:     b.stderrCount.data(b.stderr.bytes()),
+		ExitStatus: r.ExitStatus,
+		DurationMs: r.Duration.Milliseconds(),
+	}
+	if r.Err != nil {
+		stage.Error = r.Err.Error()
+	}
+	b.stages = append(b.stages, stage)
+	b.input = stage.Output
+	if b.watch != nil {
+		b.watch.done(i, stage)
+	}
+}
+
+// printBatch prints the report of the stages as text, or as JSON with
+// --output-format json.
+func printBatch(w io.Writer, stages []batchStage) error {
+	if jsonOutput() {
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(struct {
+			Stages []batchStage `json:"stages"`
+		}{stages})
+	}
+
+	for i, s := range stages {
+		fmt.Fprintf(w, "stage %d: %s\n", i+1, s.Command)
+		fmt.Fprintf(w, "  exit status %d, %dms\n", s.ExitStatus, s.DurationMs)
+		if s.Error != "" {
+			fmt.Fprintf(w, "  error: %s\n", s.Error)
+		}
+		printBatchData(w, "input", s.Input)
+		printBatchData(w, "output", s.Output)
+		if s.Stderr.Bytes > 0 {
+			printBatchData(w, "stderr", s.Stderr)
+		}
+	}
+	return nil
+}
+
+func printBatchData(w io.Writer, name string, d batchData) {
+	fmt.Fprintf(w, "  %s: %d lines, %d bytes\n", name, d.Lines, d.Bytes)
+	if d.Head == "" {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(d.Head, "\n"), "\n") {
+		fmt.Fprintf(w, "    | %s\n", line)
+	}
+}
+
+// batchStatus returns the exit status of tp in the batch mode, which is that
+// of the last stage run.
+func batchStatus(stages []batchStage) int {
+	if len(stages) == 0 {
+		return 0
+	}
+	last := stages[len(stages)-1]
+	if last.Error != "" {
+		return 1
+	}
+	return last.ExitStatus
+}
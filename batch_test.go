@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestRunBatch(t *testing.T) {
+	shell = "sh"
+	batchLines = 2
+	input := storeBytes([]byte("b\na\nb\nc"))
+
+	stages := runBatch(context.Background(), "sort | uniq | grep -v c | awk 'END { print NR }'", input)
+	expected := []struct {
+		input  batchData
+		output batchData
+		status int
+	}{
+		{input: batchData{Head: "b\na\n", Lines: 4, Bytes: 7}, output: batchData{Head: "a\nb\n", Lines: 4, Bytes: 8}},
+		{input: batchData{Head: "a\nb\n", Lines: 4, Bytes: 8}, output: batchData{Head: "a\nb\n", Lines: 3, Bytes: 6}},
+		{input: batchData{Head: "a\nb\n", Lines: 3, Bytes: 6}, output: batchData{Head: "a\nb\n", Lines: 2, Bytes: 4}},
+		{input: batchData{Head: "a\nb\n", Lines: 2, Bytes: 4}, output: batchData{Head: "2\n", Lines: 1, Bytes: 2}},
+	}
+	if len(stages) != len(expected) {
+		t.Fatalf("result: %v", stages)
+	}
+	for i, e := range expected {
+		s := stages[i]
+		if s.Input != e.input || s.Output != e.output || s.ExitStatus != e.status {
+			t.Errorf("\nresult:   %v %v %d\nexpected: %v %v %d", s.Input, s.Output, s.ExitStatus, e.input, e.output, e.status)
+		}
+	}
+
+	stages = runBatch(context.Background(), "cat | echo e >&2; exit 2", input)
+	if batchStatus(stages) != 2 || stages[1].Stderr.Head != "e\n" {
+		t.Errorf("result: %d %v", batchStatus(stages), stages[1].Stderr)
+	}
+}
+
+func TestPrintBatch(t *testing.T) {
+	stages := []batchStage{{
+		Command:    "grep a",
+		Input:      batchData{Head: "a\nb\n", Lines: 2, Bytes: 4},
+		Output:     batchData{Head: "a\n", Lines: 1, Bytes: 2},
+		ExitStatus: 0,
+		DurationMs: 3,
+	}}
+	b := new(bytes.Buffer)
+	printBatch(b, stages)
+	expected := "stage 1: grep a\n  exit status 0, 3ms\n  input: 2 lines, 4 bytes\n    | a\n    | b\n  output: 1 lines, 2 bytes\n    | a\n"
+	if b.String() != expected {
+		t.Errorf("\nresult:   %q\nexpected: %q", b.String(), expected)
+	}
+}
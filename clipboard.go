@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// clipboardCommands are used to copy when the terminal doesn't support
+// OSC 52, if the display server in env is running.
+var clipboardCommands = []struct {
+	env  string
+	args []string
+}{
+	{env: "WAYLAND_DISPLAY", args: []string{"wl-copy"}},
+	{env: "DISPLAY", args: []string{"xclip", "-selection", "clipboard"}},
+}
+
+// clipboardCommand returns the first of clipboardCommands which is available,
+// or nil.
+func clipboardCommand() []string {
+	for _, c := range clipboardCommands {
+		if os.Getenv(c.env) == "" {
+			continue
+		}
+		if _, err := exec.LookPath(c.args[0]); err == nil {
+			return c.args
+		}
+	}
+	return nil
+}
+
+// osc52 returns the OSC 52 sequence which sets the clipboard to b. The
+// terminal sets its clipboard, so it also works over SSH.
+func osc52(b []byte) string {
+	return "\x1b]52;c;" + base64.StdEncoding.EncodeToString(b) + "\a"
+}
+
+// writeOSC52 sets the clipboard of the terminal to b. It's written to the
+// terminal as stdout of tp is usually a pipe.
+func writeOSC52(b []byte) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer tty.Close()
+	_, err = tty.WriteString(osc52(b))
+	return err
+}
+
+// supportsOSC52 reports whether the terminal may support OSC 52. The Linux
+// console and dumb terminals don't.
+func supportsOSC52() bool {
+	switch os.Getenv("TERM") {
+	case "", "dumb", "linux":
+		return false
+	}
+	return true
+}
+
+// copyToClipboard copies b with OSC 52, which also works over SSH, or with
+// wl-copy or xclip where OSC 52 isn't available.
+func copyToClipboard(b []byte) error {
+	if supportsOSC52() {
+		return writeOSC52(b)
+	}
+	if args := clipboardCommand(); args != nil {
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdin = bytes.NewReader(b)
+		return cmd.Run()
+	}
+	return errors.New("OSC 52 isn't supported and neither wl-copy nor xclip is available")
+}
+
+// selection is a range of lines selected in a view pane to copy. The cursor
+// is the line moved with the arrow keys, and the anchor the line where the
+// selection started.
+type selection struct {
+	anchor int
+	cursor int
+}
+
+func (s *selection) lines() (int, int) {
+	return min(s.anchor, s.cursor), max(s.anchor, s.cursor)
+}
+
+// toggleSelection starts a selection at the first line shown, or cancels it.
+func (v *viewPane) toggleSelection() {
+	if v.selection != nil {
+		v.selection = nil
+		return
+	}
+	row, _ := v.GetScrollOffset()
+	v.selection = &selection{anchor: row, cursor: row}
+}
+
+// moveSelection moves the cursor of the selection by delta lines, scrolling
+// the pane to keep it shown.
+func (v *viewPane) moveSelection(delta int) {
+	s := v.selection
+	s.cursor = min(max(s.cursor+delta, 0), max(v.lineCount()-1, 0))
+
+	_, _, _, height := v.GetInnerRect()
+	row, column := v.GetScrollOffset()
+	switch {
+	case s.cursor < row:
+		v.ScrollTo(s.cursor, column)
+	case height > 0 && s.cursor >= row+height:
+		v.ScrollTo(s.cursor-height+1, column)
+	}
+}
+
+// selectedText returns the lines selected in the pane, or all of its text.
+func (v *viewPane) selectedText() string {
+	text := v.GetText(true)
+	if v.selection == nil {
+		return text
+	}
+	lines := strings.SplitAfter(text, "\n")
+	from, to := v.selection.lines()
+	from, to = min(from, len(lines)), min(to+1, len(lines))
+	return strings.Join(lines[from:to], "")
+}
+
+// drawSelection highlights the selected lines shown in the pane with the
+// selection color of the theme, or reverses them without one.
+func (v *viewPane) drawSelection(screen tcell.Screen) {
+	if v.selection == nil {
+		return
+	}
+	highlight := func(style tcell.Style) tcell.Style {
+		return style.Reverse(true)
+	}
+	if tag(currentTheme.Selection) != "" {
+		highlight = func(style tcell.Style) tcell.Style {
+			return style.Background(color(currentTheme.Selection))
+		}
+	}
+	x, y, width, height := v.GetInnerRect()
+	row, _ := v.GetScrollOffset()
+	from, to := v.selection.lines()
+	for i := max(from-row, 0); i < height && row+i <= to; i++ {
+		for j := 0; j < width; j++ {
+			c, comb, style, w := screen.GetContent(x+j, y+i)
+			screen.SetContent(x+j, y+i, c, comb, highlight(style))
+			if w > 1 {
+				j += w - 1
+			}
+		}
+	}
+}
+
+// copyPane copies the selected lines of the pane, or all of its text, and
+// shows the result in its title.
+func (v *viewPane) copyPane() {
+	text := v.selectedText()
+	v.selection = nil
+	if err := copyToClipboard([]byte(text)); err != nil {
+		v.SetTitle(v.title() + " " + currentTheme.errorTag() + "clipboard: " + tview.Escape(err.Error()))
+		return
+	}
+	v.SetTitle(v.title() + fmt.Sprintf(" (copied %d lines)", strings.Count(text, "\n")))
+}
+
+// copyPipeline copies the whole pipeline built so far.
+func (t *tui) copyPipeline() {
+	v := t.targetViewPane()
+	if err := copyToClipboard([]byte(t.pipelineText())); err != nil {
+		v.SetTitle(v.title() + " " + currentTheme.errorTag() + "clipboard: " + tview.Escape(err.Error()))
+		return
+	}
+	v.SetTitle(v.title() + " (copied the pipeline)")
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestOSC52(t *testing.T) {
+	cases := []struct {
+		input  string
+		result string
+	}{
+		{input: "", result: "\x1b]52;c;\a"},
+		{input: "ls | wc -l\n", result: "\x1b]52;c;bHMgfCB3YyAtbAo=\a"},
+	}
+	for _, tc := range cases {
+		result := osc52([]byte(tc.input))
+		if result != tc.result {
+			t.Errorf("\nresult:   %q\nexpected: %q", result, tc.result)
+		}
+	}
+}
+
+func TestClipboardCommand(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "xclip"), []byte("#!/bin/sh\n"), 0o755)
+	t.Setenv("PATH", dir)
+
+	cases := []struct {
+		wayland string
+		display string
+		result  []string
+	}{
+		{wayland: "", display: "", result: nil},
+		{wayland: "", display: ":0", result: []string{"xclip", "-selection", "clipboard"}},
+		{wayland: "wayland-0", display: ":0", result: []string{"xclip", "-selection", "clipboard"}},
+		{wayland: "wayland-0", display: "", result: nil},
+	}
+	for _, tc := range cases {
+		t.Setenv("WAYLAND_DISPLAY", tc.wayland)
+		t.Setenv("DISPLAY", tc.display)
+		result := clipboardCommand()
+		if !reflect.DeepEqual(result, tc.result) {
+			t.Errorf("\nresult:   %q\nexpected: %q", result, tc.result)
+		}
+	}
+}
+
+func TestCopyToClipboard(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	os.WriteFile(filepath.Join(dir, "xclip"), []byte("#!/bin/sh\ncat > "+out+"\n"), 0o755)
+	t.Setenv("TERM", "linux")
+	t.Setenv("PATH", dir+":/usr/bin:/bin")
+	t.Setenv("WAYLAND_DISPLAY", "")
+	t.Setenv("DISPLAY", "")
+
+	if err := copyToClipboard([]byte("a\n")); err == nil {
+		t.Errorf("expected an error without a clipboard")
+	}
+
+	t.Setenv("DISPLAY", ":0")
+	if err := copyToClipboard([]byte("a\n")); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(out); string(b) != "a\n" {
+		t.Errorf("result: %q", b)
+	}
+}
+
+func TestSelection(t *testing.T) {
+	useTheme(t, "monochrome")
+	screen := tcell.NewSimulationScreen("")
+	screen.Init()
+	screen.SetSize(10, 5)
+
+	v := newViewPane("stdout")
+	v.SetRect(0, 0, 10, 5)
+	v.SetText("a\nb\nc\nd\ne\nf\n")
+	v.Draw(screen)
+
+	cases := []struct {
+		delta  int
+		result string
+		row    int
+	}{
+		{delta: 0, result: "a\n", row: 0},
+		{delta: 2, result: "a\nb\nc\n", row: 0},
+		{delta: 2, result: "a\nb\nc\nd\ne\n", row: 2},
+		{delta: 10, result: "a\nb\nc\nd\ne\nf\n", row: 4},
+	}
+	v.toggleSelection()
+	for _, tc := range cases {
+		v.moveSelection(tc.delta)
+		row, _ := v.GetScrollOffset()
+		if result := v.selectedText(); result != tc.result || row != tc.row {
+			t.Errorf("\nresult:   %q %d\nexpected: %q %d", result, row, tc.result, tc.row)
+		}
+	}
+
+	v.Draw(screen)
+	screen.Show()
+	for y, reverse := range []bool{true, true, true} {
+		_, _, style, _ := screen.GetContent(1, y+1)
+		if _, _, attr := style.Decompose(); (attr&tcell.AttrReverse != 0) != reverse {
+			t.Errorf("line %d: reverse %v", y, !reverse)
+		}
+	}
+
+	useTheme(t, "dark")
+	v.Draw(screen)
+	screen.Show()
+	if _, _, style, _ := screen.GetContent(1, 1); style != style.Background(tcell.ColorYellow) {
+		t.Errorf("result: %v", style)
+	}
+
+	v.toggleSelection()
+	if v.selectedText() != "a\nb\nc\nd\ne\nf\n" {
+		t.Errorf("result: %q", v.selectedText())
+	}
+}
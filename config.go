@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// config is loaded from $XDG_CONFIG_HOME/tp/config.json (~/.config/tp/config.json).
+// Command line flags take precedence over it.
+type config struct {
+	Vi             bool                       `json:"vi"`
+	Layout         string                     `json:"layout"`
+	SplitRatio     int                        `json:"split_ratio"`
+	Scrollback     int                        `json:"scrollback"`
+	Theme          string                     `json:"theme"`
+	Themes         map[string]json.RawMessage `json:"themes"`
+	Stream         bool                       `json:"stream"`
+	StreamBuffer   int                        `json:"stream_buffer"`
+	Refresh        string                     `json:"refresh"`
+	SpillThreshold int                        `json:"spill_threshold"`
+}
+
+func defaultConfig() config {
+	return config{
+		Layout:         "horizontal",
+		SplitRatio:     50,
+		Scrollback:     1000,
+		Theme:          "dark",
+		StreamBuffer:   64,
+		Refresh:        "1s",
+		SpillThreshold: 64,
+	}
+}
+
+func configPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, name, "config.json")
+}
+
+func loadConfig(path string) (config, error) {
+	c := defaultConfig()
+	if path == "" {
+		return c, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	cases := []struct {
+		content string
+		result  config
+		isError bool
+	}{
+		{content: "", result: defaultConfig(), isError: false},
+		{content: `{"layout": "vertical", "split_ratio": 30}`, result: config{Layout: "vertical", SplitRatio: 30, Scrollback: 1000, Theme: "dark", StreamBuffer: 64, Refresh: "1s", SpillThreshold: 64}, isError: false},
+		{content: `{"vi": true}`, result: config{Vi: true, Layout: "horizontal", SplitRatio: 50, Scrollback: 1000, Theme: "dark", StreamBuffer: 64, Refresh: "1s", SpillThreshold: 64}, isError: false},
+		{content: `{"vi": }`, result: defaultConfig(), isError: true},
+	}
+	for _, tc := range cases {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if tc.content != "" {
+			os.WriteFile(path, []byte(tc.content), 0o644)
+		}
+		c, err := loadConfig(path)
+		if (err != nil) != tc.isError {
+			t.Errorf("content: %s, error: %v", tc.content, err)
+		}
+		if !reflect.DeepEqual(c, tc.result) {
+			t.Errorf("result: %+v, expected: %+v", c, tc.result)
+		}
+	}
+}
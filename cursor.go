@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// cursorFlag is the cursor position in runes in the initial command given
+// with --cursor, or -1.
+var cursorFlag = -1
+
+// cursorStage splits text at the stage with the cursor. It returns the
+// command up to the end of that stage, the cursor position within the stage,
+// and the stages after it, which are kept as they are.
+func cursorStage(text string, cursor int) (string, int, string) {
+	if cursor < 0 {
+		return text, -1, ""
+	}
+	runes := []rune(text)
+	cursor = min(cursor, len(runes))
+	head, tail := string(runes[:cursor]), string(runes[cursor:])
+
+	command, rest := text, ""
+	if i := strings.Index(tail, "|"); i >= 0 {
+		command, rest = head+tail[:i], tail[i:]
+	}
+	if i := strings.LastIndex(head, "|"); i >= 0 {
+		cursor -= utf8.RuneCountInString(head[:i+1])
+	}
+	return command, cursor, rest
+}
+
+// commandCursor returns the cursor position in runes in the whole command.
+func (c *cliPane) commandCursor() int {
+	return utf8.RuneCountInString(adjustPipe(c.prompt)) + c.cursor()
+}
+
+// printCommand prints the command for -c, preceded by a line with the cursor
+// position in runes with --cursor.
+func printCommand(text string, cursor int) {
+	if cursorFlag >= 0 {
+		fmt.Println(cursor)
+	}
+	fmt.Println(text)
+}
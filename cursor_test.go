@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+func TestCursorStage(t *testing.T) {
+	cases := []struct {
+		text    string
+		cursor  int
+		command string
+		pos     int
+		rest    string
+	}{
+		{text: "ls | grep a", cursor: -1, command: "ls | grep a", pos: -1, rest: ""},
+		{text: "ls | grep a", cursor: 11, command: "ls | grep a", pos: 7, rest: ""},
+		{text: "ls | grep a", cursor: 99, command: "ls | grep a", pos: 7, rest: ""},
+		{text: "ls | grep a | wc", cursor: 6, command: "ls | grep a ", pos: 2, rest: "| wc"},
+		{text: "ls || wc", cursor: 4, command: "ls |", pos: 0, rest: "| wc"},
+		{text: "echo あい | cat", cursor: 6, command: "echo あい ", pos: 6, rest: "| cat"},
+	}
+	for _, tc := range cases {
+		command, pos, rest := cursorStage(tc.text, tc.cursor)
+		if command != tc.command || pos != tc.pos || rest != tc.rest {
+			t.Errorf("\nresult:   %q %d %q\nexpected: %q %d %q", command, pos, rest, tc.command, tc.pos, tc.rest)
+		}
+	}
+}
+
+func TestCommandCursor(t *testing.T) {
+	defer func() {
+		initCommand, cursorFlag = "", -1
+	}()
+	initCommand, cursorFlag = "cat a | grep x | sort", 10
+
+	screen := tcell.NewSimulationScreen("")
+	screen.Init()
+	defer screen.Fini()
+
+	c := newCliPane()
+	c.SetRect(0, 0, 80, 1)
+	c.Draw(screen)
+	c.setCursor(c.initCursor)
+	if c.prompt != "cat a " || c.GetText() != " grep x " || c.rest != "| sort" || c.commandCursor() != 10 {
+		t.Errorf("result: %q %q %q %d", c.prompt, c.GetText(), c.rest, c.commandCursor())
+	}
+}
+
+func TestCursorProbe(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	screen.Init()
+	defer screen.Fini()
+
+	// The text may contain the first probe rune, such as a Nerd Font icon.
+	c := newCliPane()
+	c.SetRect(0, 0, 80, 1)
+	c.Draw(screen)
+	c.SetText("echo \uE000\uE001 x")
+	for _, pos := range []int{0, 6, 9} {
+		c.setCursor(pos)
+		if result := c.cursor(); result != pos || c.GetText() != "echo \uE000\uE001 x" {
+			t.Errorf("\nresult:   %q %d\nexpected: %q %d", c.GetText(), result, "echo \uE000\uE001 x", pos)
+		}
+	}
+
+	// The icon can be typed.
+	c.InputHandler()(tcell.NewEventKey(tcell.KeyRune, '\uE000', tcell.ModNone), func(p tview.Primitive) {})
+	if c.GetText() != "echo \uE000\uE001 x\uE000" {
+		t.Errorf("result: %q", c.GetText())
+	}
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"github.com/rivo/tview"
+	"github.com/rivo/uniseg"
+)
+
+type diffMode int
+
+const (
+	diffOff diffMode = iota
+	diffUnified
+	diffSideBySide
+)
+
+var diffModeNames = []string{"off", "unified", "side-by-side"}
+
+type diffOp int
+
+const (
+	diffEqual diffOp = iota
+	diffDelete
+	diffInsert
+	diffChange
+)
+
+type diffEdit struct {
+	op   diffOp
+	text string
+}
+
+// maxDiffEdits is the number of edits beyond which diff gives up, as the
+// time and memory it takes grow with the number of edits.
+var maxDiffEdits = 1000
+
+// errTooManyDiffs is returned when the lines differ by more than maxDiffEdits.
+var errTooManyDiffs = errors.New("too many differences")
+
+// diff returns the shortest edit script from a to b using Myers' algorithm,
+// or false if it's longer than maxDiffEdits.
+func diff(a, b []string) ([]diffEdit, bool) {
+	n, m := len(a), len(b)
+	limit := n + m
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	// trace keeps the diagonals -d-1 to d+1 of v before each round d.
+	var trace [][]int
+
+	for d := 0; d <= min(limit, maxDiffEdits); d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, d), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func backtrack(a, b []string, trace [][]int, d int) []diffEdit {
+	var edits []diffEdit
+	x, y := len(a), len(b)
+	for ; d > 0; d-- {
+		v, offset := trace[d], d+1
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, diffEdit{op: diffEqual, text: a[x]})
+		}
+		if x == prevX {
+			y--
+			edits = append(edits, diffEdit{op: diffInsert, text: b[y]})
+		} else {
+			x--
+			edits = append(edits, diffEdit{op: diffDelete, text: a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		edits = append(edits, diffEdit{op: diffEqual, text: a[x]})
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// diffSegment is a part of a line, changed if it differs from the other side.
+type diffSegment struct {
+	text    string
+	changed bool
+}
+
+// diffLine is a line of a diff. Both sides are set for equal and changed
+// lines, only old for deleted and only new for inserted lines.
+type diffLine struct {
+	op       diffOp
+	old, new []diffSegment
+}
+
+// diffText compares a and b line by line. Deleted lines followed by inserted
+// lines are paired up as changed lines. With words, the changed words of
+// paired lines are marked as changed segments.
+func diffText(a, b []byte, words bool) ([]diffLine, error) {
+	var lines []diffLine
+	var deleted, inserted []string
+
+	flush := func() {
+		for i := 0; i < len(deleted) || i < len(inserted); i++ {
+			switch {
+			case i >= len(inserted):
+				lines = append(lines, diffLine{op: diffDelete, old: []diffSegment{{text: deleted[i]}}})
+			case i >= len(deleted):
+				lines = append(lines, diffLine{op: diffInsert, new: []diffSegment{{text: inserted[i]}}})
+			case words:
+				old, new := diffWords(deleted[i], inserted[i])
+				lines = append(lines, diffLine{op: diffChange, old: old, new: new})
+			default:
+				lines = append(lines, diffLine{
+					op:  diffChange,
+					old: []diffSegment{{text: deleted[i]}},
+					new: []diffSegment{{text: inserted[i]}},
+				})
+			}
+		}
+		deleted, inserted = nil, nil
+	}
+
+	edits, ok := diff(splitLines(a), splitLines(b))
+	if !ok {
+		return nil, errTooManyDiffs
+	}
+	for _, e := range edits {
+		switch e.op {
+		case diffDelete:
+			if len(inserted) > 0 {
+				flush()
+			}
+			deleted = append(deleted, e.text)
+		case diffInsert:
+			inserted = append(inserted, e.text)
+		default:
+			flush()
+			segment := []diffSegment{{text: e.text}}
+			lines = append(lines, diffLine{op: diffEqual, old: segment, new: segment})
+		}
+	}
+	flush()
+	return lines, nil
+}
+
+// diffWords marks the changed words of a and b, or the whole lines if they
+// have too many differences.
+func diffWords(a, b string) (old, new []diffSegment) {
+	edits, ok := diff(splitWords(a), splitWords(b))
+	if !ok {
+		return []diffSegment{{text: a, changed: true}}, []diffSegment{{text: b, changed: true}}
+	}
+	for _, e := range edits {
+		switch e.op {
+		case diffEqual:
+			old = append(old, diffSegment{text: e.text})
+			new = append(new, diffSegment{text: e.text})
+		case diffDelete:
+			old = append(old, diffSegment{text: e.text, changed: true})
+		case diffInsert:
+			new = append(new, diffSegment{text: e.text, changed: true})
+		}
+	}
+	return old, new
+}
+
+func splitLines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	return strings.Split(string(bytes.TrimSuffix(b, []byte("\n"))), "\n")
+}
+
+// splitWords splits s into runs of characters of the same class
+// (blank, keyword or punctuation) as used by the vi word motions.
+func splitWords(s string) []string {
+	var words []string
+	r := []rune(s)
+	start := 0
+	for i := 1; i <= len(r); i++ {
+		if i == len(r) || charClass(r[i], false) != charClass(r[start], false) {
+			words = append(words, string(r[start:i]))
+			start = i
+		}
+	}
+	return words
+}
+
+// diffColors returns the color tags for deleted and inserted lines, and
+// the tags marking changed words.
+func diffColors() (deleted, inserted, mark, unmark string) {
+	return tag(currentTheme.Deleted), tag(currentTheme.Inserted), "[::r]", "[::-]"
+}
+
+func renderSegments(segments []diffSegment, width int) string {
+	_, _, mark, unmark := diffColors()
+	var b strings.Builder
+	w := 0
+	for _, s := range segments {
+		text := s.text
+		if width >= 0 {
+			text = truncate(text, width-w)
+			w += uniseg.StringWidth(text)
+		}
+		if s.changed {
+			b.WriteString(mark + tview.Escape(text) + unmark)
+		} else {
+			b.WriteString(tview.Escape(text))
+		}
+	}
+	if width >= 0 && w < width {
+		b.WriteString(strings.Repeat(" ", width-w))
+	}
+	return b.String()
+}
+
+// truncate cuts s to the given display width.
+func truncate(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	w := 0
+	g := uniseg.NewGraphemes(s)
+	for g.Next() {
+		if w+g.Width() > width {
+			start, _ := g.Positions()
+			return s[:start]
+		}
+		w += g.Width()
+	}
+	return s
+}
+
+// renderUnified renders lines as a unified diff.
+func renderUnified(lines []diffLine) string {
+	deleted, inserted, _, _ := diffColors()
+	var b strings.Builder
+	for _, l := range lines {
+		if l.op == diffEqual {
+			b.WriteString("  " + renderSegments(l.old, -1) + "\n")
+			continue
+		}
+		if l.old != nil {
+			b.WriteString(deleted + "- " + renderSegments(l.old, -1) + "[-]\n")
+		}
+		if l.new != nil {
+			b.WriteString(inserted + "+ " + renderSegments(l.new, -1) + "[-]\n")
+		}
+	}
+	return b.String()
+}
+
+// renderSideBySide renders lines in two columns fitting into width.
+func renderSideBySide(lines []diffLine, width int) string {
+	deleted, inserted, _, _ := diffColors()
+	w := max((width-3)/2, 1)
+	var b strings.Builder
+	for _, l := range lines {
+		marker := map[diffOp]string{diffEqual: " ", diffDelete: "<", diffInsert: ">", diffChange: "|"}[l.op]
+		left, right := renderSegments(l.old, w), renderSegments(l.new, w)
+		if l.op != diffEqual {
+			left = deleted + left + "[-]"
+			right = inserted + right + "[-]"
+		}
+		b.WriteString(left + " " + marker + " " + right + "\n")
+	}
+	return b.String()
+}
+
+func (so *stdoutViewPane) cycleDiff() {
+	so.diffMode = (so.diffMode + 1) % diffMode(len(diffModeNames))
+	so.updateName()
+}
+
+func (so *stdoutViewPane) toggleDiffWords() {
+	so.diffWords = !so.diffWords
+	so.updateName()
+}
+
+func (so *stdoutViewPane) togglePrevDiff() {
+	so.diffPrev = !so.diffPrev
+	so.updateName()
+}
+
+// diffing reports whether the pane shows a diff instead of the output.
+func (so *stdoutViewPane) diffing() bool {
+	return so.diffMode != diffOff || so.diffPrev
+}
+
+func (so *stdoutViewPane) updateName() {
+	so.syncUpdate(func() {
+		if !so.diffing() {
+			so.name = "stdout/stderr"
+			return
+		}
+		base, mode, granularity := "stdin", diffModeNames[diffUnified], "line"
+		if so.diffPrev {
+			base = "previous"
+		}
+		if so.diffMode != diffOff {
+			mode = diffModeNames[so.diffMode]
+		}
+		if so.diffWords {
+			granularity = "word"
+		}
+		so.name = "diff " + base + "/stdout (" + mode + ", " + granularity + ")"
+	})
+}
+
+// render redraws the output of the last command, or the diff against
+// its input or the previous output.
+func (so *stdoutViewPane) render() {
+	if so.diffing() {
+		so.renderDiff()
+		return
+	}
+
+	var output []byte
+	so.syncUpdate(func() {
+		output = so.data
+	})
+	so.redraw(output)
+}
+
+// output returns the output of the last command, or nil while the pane
+// shows a diff.
+func (so *stdoutViewPane) output() []byte {
+	if so.diffing() {
+		return nil
+	}
+	var output []byte
+	so.syncUpdate(func() {
+		output = so.data
+	})
+	return output
+}
+
+// renderDiff shows the diff between the input (or the previous output) and
+// the output of the last command. Only the lines kept for display are compared.
+func (so *stdoutViewPane) renderDiff() {
+	var input, output []byte
+	so.syncUpdate(func() {
+		input, output = so.input, so.data
+		if so.diffPrev {
+			input = so.prevData
+		}
+	})
+	limit := max(getTerminalHeight()-3, scrollback)
+	lines, err := diffText(headLines(input, limit), headLines(output, limit), so.diffWords)
+	if err != nil {
+		so.SetText(currentTheme.errorTag() + "(" + err.Error() + ")[-::-]")
+		return
+	}
+
+	if so.diffMode == diffSideBySide {
+		_, _, width, _ := so.GetInnerRect()
+		so.SetText(renderSideBySide(lines, width))
+		return
+	}
+	so.SetText(renderUnified(lines))
+}
+
+// headLines returns the first n lines of b.
+func headLines(b []byte, n int) []byte {
+	i := 0
+	for ; n > 0; n-- {
+		j := bytes.IndexByte(b[i:], '\n')
+		if j == -1 {
+			return b
+		}
+		i += j + 1
+	}
+	return b[:i]
+}
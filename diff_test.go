@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	cases := []struct {
+		a      string
+		b      string
+		result string
+	}{
+		{a: "", b: "", result: ""},
+		{a: "a b c", b: "a b c", result: "=a =b =c"},
+		{a: "a b c", b: "a c", result: "=a -b =c"},
+		{a: "a c", b: "a b c", result: "=a +b =c"},
+		{a: "a b c a b b a", b: "c b a b a c", result: "-a -b =c +b =a =b -b =a +c"},
+	}
+	for _, tc := range cases {
+		var result []string
+		edits, _ := diff(strings.Fields(tc.a), strings.Fields(tc.b))
+		for _, e := range edits {
+			result = append(result, map[diffOp]string{diffEqual: "=", diffDelete: "-", diffInsert: "+"}[e.op]+e.text)
+		}
+		if strings.Join(result, " ") != tc.result {
+			t.Errorf("result: %s, expected: %s", strings.Join(result, " "), tc.result)
+		}
+	}
+}
+
+func TestMaxDiffEdits(t *testing.T) {
+	defer func() {
+		maxDiffEdits = 1000
+	}()
+	maxDiffEdits = 2
+
+	if _, ok := diff(strings.Fields("a b c"), strings.Fields("a x c")); !ok {
+		t.Errorf("2 edits are given up")
+	}
+	if _, ok := diff(strings.Fields("a b c"), strings.Fields("x y c")); ok {
+		t.Errorf("4 edits are not given up")
+	}
+	if _, err := diffText([]byte("a\nb\n"), []byte("c\nd\n"), false); err != errTooManyDiffs {
+		t.Errorf("result: %v", err)
+	}
+	old, new := diffWords("a b", "c d")
+	if len(old) != 1 || !old[0].changed || len(new) != 1 || !new[0].changed {
+		t.Errorf("result: %v %v", old, new)
+	}
+}
+
+func TestRenderDiff(t *testing.T) {
+	useTheme(t, "monochrome")
+	a := "foo 1\nbar 2\nbaz 3\n"
+	b := "foo 1\nbar 20\nqux 4\n"
+
+	cases := []struct {
+		words  bool
+		split  bool
+		result string
+	}{
+		{
+			words:  false,
+			result: "  foo 1\n- bar 2[-]\n+ bar 20[-]\n- baz 3[-]\n+ qux 4[-]\n",
+		},
+		{
+			words:  true,
+			result: "  foo 1\n- bar [::r]2[::-][-]\n+ bar [::r]20[::-][-]\n- [::r]baz[::-] [::r]3[::-][-]\n+ [::r]qux[::-] [::r]4[::-][-]\n",
+		},
+		{
+			words:  false,
+			split:  true,
+			result: "foo 1     foo 1  \nbar 2  [-] | bar 20 [-]\nbaz 3  [-] | qux 4  [-]\n",
+		},
+	}
+	for _, tc := range cases {
+		lines, _ := diffText([]byte(a), []byte(b), tc.words)
+		var result string
+		if tc.split {
+			result = renderSideBySide(lines, 17)
+		} else {
+			result = renderUnified(lines)
+		}
+		if result != tc.result {
+			t.Errorf("\nresult:   %q\nexpected: %q", result, tc.result)
+		}
+	}
+}
+
+func TestPrevDiff(t *testing.T) {
+	shell = "sh"
+	useTheme(t, "monochrome")
+	getTerminalHeight = func() int {
+		return 6
+	}
+
+	so := newStdoutViewPane()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	so.execCommand(ctx, "printf 'a\\nb\\n'", storeBytes(nil))
+	so.execCommand(ctx, "printf 'a\\nc\\n'", storeBytes(nil))
+	so.togglePrevDiff()
+	so.render()
+
+	if so.name != "diff previous/stdout (unified, line)" {
+		t.Errorf("result: %s", so.name)
+	}
+	if so.GetText(false) != "  a\n- b[-]\n+ c[-]\n" {
+		t.Errorf("result: %q", so.GetText(false))
+	}
+
+	so.togglePrevDiff()
+	so.render()
+	if so.name != "stdout/stderr" || so.GetText(true) != "a\nc\n" {
+		t.Errorf("result: %s %q", so.name, so.GetText(true))
+	}
+}
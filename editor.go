@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// newlineSymbol stands for a newline in the command line, which is a single
+// row. The text is run and returned with the newlines restored.
+const newlineSymbol = "⏎"
+
+// openTTY opens the terminal the editor is attached to.
+var openTTY = func() (*os.File, error) {
+	return os.OpenFile("/dev/tty", os.O_RDWR, 0)
+}
+
+func showNewlines(text string) string {
+	return strings.ReplaceAll(text, "\n", newlineSymbol)
+}
+
+func restoreNewlines(text string) string {
+	return strings.ReplaceAll(text, newlineSymbol, "\n")
+}
+
+// editor returns the command line of the user's editor.
+func editor() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if e := strings.Fields(os.Getenv(env)); len(e) > 0 {
+			return e
+		}
+	}
+	return []string{"vi"}
+}
+
+// editText opens text in the user's editor via a temp file and returns the
+// edited text. The editor is attached to the terminal because stdin and
+// stdout of tp are usually pipes.
+func editText(text string) (string, error) {
+	f, err := os.CreateTemp("", name+"-*.sh")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(text + "\n"); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	tty, err := openTTY()
+	if err != nil {
+		return "", err
+	}
+	defer tty.Close()
+
+	e := editor()
+	cmd := exec.Command(e[0], append(e[1:], f.Name())...)
+	cmd.Stdin = tty
+	cmd.Stdout = tty
+	cmd.Stderr = tty
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\n"), nil
+}
+
+// editStage suspends the application while the current stage is edited in
+// the user's editor, then reloads the stage and re-runs the preview.
+func (t *tui) editStage() {
+	var text string
+	var err error
+	if !t.Suspend(func() {
+		text, err = editText(restoreNewlines(t.cliPane.GetText()))
+	}) {
+		return
+	}
+	if err != nil {
+		t.stdoutPane.SetTitle(currentTheme.errorTag() + "editor: " + tview.Escape(err.Error()))
+		return
+	}
+	t.cliPane.SetText(showNewlines(text))
+}
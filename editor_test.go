@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestEditStage(t *testing.T) {
+	// The editor keeps what it reads and writes a multi-line awk program.
+	dir := t.TempDir()
+	script := filepath.Join(dir, "editor")
+	read := filepath.Join(dir, "read")
+	program := "awk '{\n  print $1\n}'"
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncp \"$1\" "+read+"\nprintf '%s\\n' \"awk '{\" '  print $1' \"}'\" > \"$1\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", script)
+	t.Cleanup(func() {
+		openTTY = func() (*os.File, error) {
+			return os.OpenFile("/dev/tty", os.O_RDWR, 0)
+		}
+		stdinData = storeBytes(nil)
+	})
+	openTTY = func() (*os.File, error) {
+		return os.OpenFile(os.DevNull, os.O_RDWR, 0)
+	}
+	stdinData = storeBytes([]byte("a b\nc d\n"))
+
+	tp := startTui(t)
+	tp.stdinPane.setData(stdinData)
+	queueUpdate(t, tp, func() {
+		tp.cliPane.setPrompt("sort | ")
+		tp.editStage()
+	})
+	waitFor(t, tp, func() bool {
+		return tp.stdoutPane.GetText(true) == "a\nc\n"
+	})
+
+	screen := tcell.NewSimulationScreen("")
+	screen.Init()
+	defer screen.Fini()
+	var result string
+	queueUpdate(t, tp, func() {
+		tp.cliPane.SetRect(0, 0, 80, 1)
+		tp.cliPane.Draw(screen)
+		screen.Show()
+		result = tp.pipelineText()
+	})
+	expected := " sort |awk '{⏎  print $1⏎}'"
+	if line := screenLine(screen, 0); line != expected {
+		t.Errorf("\nresult:   %q\nexpected: %q", line, expected)
+	}
+	if expected := "sort |" + program; result != expected {
+		t.Errorf("\nresult:   %q\nexpected: %q", result, expected)
+	}
+
+	// The editor gets the newlines back.
+	queueUpdate(t, tp, func() {
+		tp.editStage()
+	})
+	b, err := os.ReadFile(read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, expected := string(b), program+"\n"; result != expected {
+		t.Errorf("\nresult:   %q\nexpected: %q", result, expected)
+	}
+}
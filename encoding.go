@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/transform"
+)
+
+// candidateEncodings are tried by detectEncoding for non-UTF-8 input.
+var candidateEncodings = []string{"Shift_JIS", "EUC-JP"}
+
+var (
+	// inputEncoding is the encoding of stdin, or nil for UTF-8.
+	inputEncoding encoding.Encoding
+	// decodeInput enables decoding inputEncoding for display.
+	decodeInput bool
+)
+
+// lookupEncoding finds an encoding by its IANA name or one of the labels
+// used on the web such as "sjis" or "latin1".
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	e, err := ianaindex.IANA.Encoding(name)
+	if err != nil {
+		e, err = htmlindex.Get(name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unknown encoding %q", name)
+	}
+	if e == nil {
+		return nil, fmt.Errorf("unsupported encoding %q", name)
+	}
+	return e, nil
+}
+
+func encodingName(e encoding.Encoding) string {
+	if e == nil {
+		return "UTF-8"
+	}
+	name, err := ianaindex.IANA.Name(e)
+	if err != nil {
+		return "unknown"
+	}
+	return name
+}
+
+// detectEncoding guesses the encoding of b. Text which isn't UTF-8 is
+// checked against the candidate encodings and otherwise taken as Latin-1.
+func detectEncoding(b []byte) string {
+	if utf8.Valid(trimIncompleteRune(b)) || bytes.IndexByte(b, 0) != -1 {
+		return "UTF-8"
+	}
+
+	best, bestScore := "ISO-8859-1", 0
+	for _, name := range candidateEncodings {
+		e, err := lookupEncoding(name)
+		if err != nil {
+			continue
+		}
+		s, err := e.NewDecoder().String(string(b))
+		if err != nil || strings.ContainsRune(s, utf8.RuneError) {
+			continue
+		}
+
+		japanese, other := 0, 0
+		for _, r := range s {
+			switch {
+			case r < utf8.RuneSelf:
+			case r >= 0xff61: // halfwidth forms
+				other++
+			case unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Han), r >= 0x3000 && r <= 0x303f, r >= 0xff01 && r <= 0xff5e:
+				japanese++
+			default:
+				other++
+			}
+		}
+		if japanese > other && japanese > bestScore {
+			best, bestScore = name, japanese
+		}
+	}
+	return best
+}
+
+// setInputEncoding sets the encoding of stdin, detecting it if name is empty.
+// With transcode, stdin is converted to UTF-8 for the commands as well.
+func setInputEncoding(name string, transcode bool) error {
+	if name == "" {
+		name = detectEncoding(stdinData.bytes())
+	}
+	e, err := lookupEncoding(name)
+	if err != nil {
+		return err
+	}
+	if encodingName(e) == "UTF-8" {
+		inputEncoding, decodeInput = nil, false
+		return nil
+	}
+	inputEncoding, decodeInput = e, true
+
+	if transcode {
+		r, err := stdinData.reader()
+		if err != nil {
+			return err
+		}
+		defer closeReader(r)
+		data := newStore(true)
+		if _, err := io.Copy(data, transform.NewReader(r, e.NewDecoder())); err != nil {
+			data.close()
+			return fmt.Errorf("failed to transcode stdin from %s: %w", encodingName(e), err)
+		}
+		stdinData.close()
+		stdinData = data
+		inputEncoding, decodeInput = nil, false
+	}
+	return nil
+}
+
+// trimIncompleteRune drops a UTF-8 rune cut at the end of b, as the head of
+// a large input may end in the middle of one.
+func trimIncompleteRune(b []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		if utf8.RuneStart(b[len(b)-i]) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				return b[:len(b)-i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+func toggleDecodeInput() {
+	if inputEncoding != nil {
+		decodeInput = !decodeInput
+	}
+}
+
+// encodingStatus returns the input encoding for the title of a pane.
+func encodingStatus() string {
+	switch {
+	case inputEncoding == nil:
+		return ""
+	case decodeInput:
+		return " (" + encodingName(inputEncoding) + ")"
+	default:
+		return " (" + encodingName(inputEncoding) + ", undecoded)"
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestDetectEncoding(t *testing.T) {
+	cases := []struct {
+		input  string
+		result string
+	}{
+		{input: "日本語のログ\n", result: "UTF-8"},
+		{input: "\x93\xfa\x96\x7b\x8c\xea\x82\xcc\x83\x8d\x83\x4f\n", result: "Shift_JIS"},
+		{input: "\xc6\xfc\xcb\xdc\xb8\xec\xa4\xce\xa5\xed\xa5\xb0\n", result: "EUC-JP"},
+		{input: "caf\xe9 d\xe9j\xe0 vu\n", result: "ISO-8859-1"},
+		{input: "\x00\xff", result: "UTF-8"},
+		// The head of a large input may end in the middle of a rune.
+		{input: "日本語のログ\n\xe3\x83", result: "UTF-8"},
+		{input: "caf\xe9\n\xe3\x83", result: "ISO-8859-1"},
+	}
+	for _, tc := range cases {
+		result := detectEncoding([]byte(tc.input))
+		if result != tc.result {
+			t.Errorf("\nresult:   %s\nexpected: %s", result, tc.result)
+		}
+	}
+}
+
+func TestSetInputEncoding(t *testing.T) {
+	getTerminalHeight = func() int {
+		return 6
+	}
+	defer func() {
+		inputEncoding, decodeInput, stdinData = nil, false, storeBytes(nil)
+	}()
+
+	sjis := []byte("\x93\xfa\x96\x7b\x8c\xea\n")
+	stdinData = storeBytes(sjis)
+	if err := setInputEncoding("", false); err != nil {
+		t.Fatal(err)
+	}
+	if encodingStatus() != " (Shift_JIS)" {
+		t.Errorf("result: %s", encodingStatus())
+	}
+
+	si := newStdinViewPane()
+	si.setData(stdinData)
+	if si.GetText(true) != "日本語\n" || !bytes.Equal(si.data.bytes(), sjis) || si.hexStatus() != "" {
+		t.Errorf("result: %q %q %q", si.GetText(true), si.data.bytes(), si.hexStatus())
+	}
+
+	toggleDecodeInput()
+	if encodingStatus() != " (Shift_JIS, undecoded)" {
+		t.Errorf("result: %s", encodingStatus())
+	}
+
+	if err := setInputEncoding("sjis", true); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stdinData.bytes(), []byte("日本語\n")) || encodingStatus() != "" {
+		t.Errorf("result: %q %s", stdinData.bytes(), encodingStatus())
+	}
+
+	if err := setInputEncoding("foo", false); err == nil {
+		t.Errorf("expected an error for an unknown encoding")
+	}
+}
+
+func TestDecodeOutput(t *testing.T) {
+	shell = "sh"
+	getTerminalHeight = func() int {
+		return 6
+	}
+	defer func() {
+		inputEncoding, decodeInput, stdinData = nil, false, storeBytes(nil)
+	}()
+
+	// Commands keep the encoding of their input, so their output is decoded.
+	sjis := []byte("\x93\xfa\x96\x7b\x8c\xea\n\x89\x70\x8c\xea\n")
+	stdinData = storeBytes(sjis)
+	if err := setInputEncoding("", false); err != nil {
+		t.Fatal(err)
+	}
+	so := newStdoutViewPane()
+	so.execCommand(context.Background(), "sort", storeBytes(sjis))
+	if result, expected := so.GetText(true), "英語\n日本語\n"; result != expected {
+		t.Errorf("\nresult:   %q\nexpected: %q", result, expected)
+	}
+
+	// Output converted to UTF-8 is shown as it is once the decoding is off.
+	toggleDecodeInput()
+	so.Clear()
+	so.execCommand(context.Background(), "iconv -f SHIFT_JIS -t UTF-8", storeBytes(sjis))
+	if result, expected := so.GetText(true), "日本語\n英語\n"; result != expected {
+		t.Errorf("\nresult:   %q\nexpected: %q", result, expected)
+	}
+}
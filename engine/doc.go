@@ -0,0 +1,18 @@
+// Package engine runs the pipelines previewed by tp. It is the part of tp
+// which doesn't depend on the terminal UI:
+//
+//   - Pipeline and Stage, the model of a command line split into stages.
+//   - Sandbox, which restricts the commands to read-only access to the file
+//     system, with Landlock on Linux, Apple Seatbelt on macOS, and None.
+//   - Executor, which runs a command or a pipeline stage by stage in a
+//     sandbox, and stops when its context is cancelled.
+//   - Sink, which receives the output and the result of each stage as it
+//     runs, and Buffer, which passes the output of a stage to the next one.
+//
+// Programs using a Landlock sandbox must call RunInSandbox first in main,
+// as the sandboxed commands are run by re-executing the program.
+package engine
+
+// APIVersion is the version of the types of this package. It's incremented
+// on incompatible changes.
+const APIVersion = 1
@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"time"
+)
+
+// waitDelay is how long Run waits for the output of a killed command. The
+// shell is killed, but its children may keep the output open.
+const waitDelay = 500 * time.Millisecond
+
+// Result is the result of a command.
+type Result struct {
+	// ExitStatus is the exit status of the command, or -1 if it was killed.
+	ExitStatus int
+	Duration   time.Duration
+	// Err is an error other than a non-zero exit status, such as a command
+	// which couldn't be started.
+	Err error
+}
+
+// StageResult is the result of a stage of a pipeline.
+type StageResult struct {
+	Stage Stage
+	Result
+}
+
+// Sink receives the output and the result of each stage run by
+// RunPipeline. Its methods are called in order for each stage: Stdout and
+// Stderr before it runs, then Done.
+type Sink interface {
+	Stdout(i int, stage Stage) io.Writer
+	Stderr(i int, stage Stage) io.Writer
+	Done(i int, result StageResult)
+}
+
+// Buffer keeps the output of a stage for the next one.
+type Buffer interface {
+	io.Writer
+	// Reader returns a reader of what has been written. It's closed after
+	// reading if it's an io.Closer.
+	Reader() (io.Reader, error)
+	// Close releases the buffer once the next stage has read it.
+	Close() error
+}
+
+// memoryBuffer is the default Buffer, which keeps the output in memory.
+type memoryBuffer struct {
+	bytes.Buffer
+}
+
+func (b *memoryBuffer) Reader() (io.Reader, error) {
+	return bytes.NewReader(b.Bytes()), nil
+}
+
+func (b *memoryBuffer) Close() error {
+	return nil
+}
+
+// NewMemoryBuffer returns a Buffer keeping the output in memory.
+func NewMemoryBuffer() Buffer {
+	return &memoryBuffer{}
+}
+
+// Executor runs commands with Shell in Sandbox.
+type Executor struct {
+	Shell   string
+	Sandbox Sandbox
+	// NewBuffer returns the buffers between the stages of RunPipeline.
+	// NewMemoryBuffer is used if it's nil.
+	NewBuffer func() Buffer
+}
+
+// Command returns a command running text in the sandbox.
+func (e *Executor) Command(ctx context.Context, text string) *exec.Cmd {
+	return e.Sandbox.Command(ctx, e.Shell, text)
+}
+
+// Run runs text on stdin in the sandbox. The command is killed when ctx is
+// done.
+func (e *Executor) Run(ctx context.Context, text string, stdin io.Reader, stdout, stderr io.Writer) Result {
+	cmd := e.Command(ctx, text)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = waitDelay
+
+	start := time.Now()
+	err := cmd.Run()
+	r := Result{Duration: time.Since(start)}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		r.ExitStatus = exitErr.ExitCode()
+	} else if err != nil {
+		r.Err = err
+	}
+	return r
+}
+
+// RunPipeline runs the stages of p one by one, the first on input and each
+// of the others on the output of the previous one. It stops at a stage which
+// can't be run, or when ctx is done. input isn't closed.
+func (e *Executor) RunPipeline(ctx context.Context, p Pipeline, input Buffer, sink Sink) []StageResult {
+	newBuffer := e.NewBuffer
+	if newBuffer == nil {
+		newBuffer = NewMemoryBuffer
+	}
+
+	var results []StageResult
+	in := input
+	defer func() {
+		if in != input {
+			in.Close()
+		}
+	}()
+	for i, stage := range p.Stages {
+		out := newBuffer()
+		result := StageResult{Stage: stage}
+		stdout, stderr := io.MultiWriter(out, sink.Stdout(i, stage)), sink.Stderr(i, stage)
+		stdin, err := in.Reader()
+		if err == nil {
+			result.Result = e.Run(ctx, stage.Command, stdin, stdout, stderr)
+			if c, ok := stdin.(io.Closer); ok {
+				c.Close()
+			}
+		} else {
+			result.Err = err
+		}
+		results = append(results, result)
+		sink.Done(i, result)
+
+		if in != input {
+			in.Close()
+		}
+		in = out
+		if result.Err != nil || ctx.Err() != nil {
+			break
+		}
+	}
+	return results
+}
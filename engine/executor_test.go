@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testSink struct {
+	stdout  []*bytes.Buffer
+	results []StageResult
+}
+
+func (s *testSink) Stdout(i int, stage Stage) io.Writer {
+	s.stdout = append(s.stdout, new(bytes.Buffer))
+	return s.stdout[i]
+}
+
+func (s *testSink) Stderr(i int, stage Stage) io.Writer {
+	return io.Discard
+}
+
+func (s *testSink) Done(i int, result StageResult) {
+	s.results = append(s.results, result)
+}
+
+func TestRun(t *testing.T) {
+	e := &Executor{Shell: "sh", Sandbox: None{}}
+	cases := []struct {
+		text   string
+		result string
+		status int
+	}{
+		{text: "tr a-z A-Z", result: "ABC\n", status: 0},
+		{text: "cat; exit 3", result: "abc\n", status: 3},
+	}
+	for _, tc := range cases {
+		b := new(bytes.Buffer)
+		r := e.Run(context.Background(), tc.text, strings.NewReader("abc\n"), b, io.Discard)
+		if b.String() != tc.result || r.ExitStatus != tc.status || r.Err != nil {
+			t.Errorf("\nresult:   %q %d %v\nexpected: %q %d", b.String(), r.ExitStatus, r.Err, tc.result, tc.status)
+		}
+	}
+}
+
+func TestRunPipeline(t *testing.T) {
+	e := &Executor{Shell: "sh", Sandbox: None{}}
+	input := NewMemoryBuffer()
+	input.Write([]byte("b\na\nb\n"))
+
+	sink := &testSink{}
+	results := e.RunPipeline(context.Background(), Parse("sort | uniq | grep -c ."), input, sink)
+	expected := []string{"a\nb\nb\n", "a\nb\n", "2\n"}
+	if len(results) != len(expected) || len(sink.results) != len(expected) {
+		t.Fatalf("result: %v", results)
+	}
+	for i, e := range expected {
+		if sink.stdout[i].String() != e || results[i].Stage.Command != sink.results[i].Stage.Command {
+			t.Errorf("\nresult:   %q\nexpected: %q", sink.stdout[i].String(), e)
+		}
+	}
+
+	// The pipeline stops once cancelled.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	results = e.RunPipeline(ctx, Parse("sleep 10 | cat"), input, &testSink{})
+	if len(results) != 1 || results[0].Duration > 5*time.Second {
+		t.Errorf("result: %v", results)
+	}
+}
+
+func TestNone(t *testing.T) {
+	if err := (None{}).Check(); err == nil {
+		t.Errorf("expected an error for no sandbox")
+	}
+}
@@ -0,0 +1,37 @@
+package engine
+
+import "strings"
+
+// Stage is a command of a pipeline.
+type Stage struct {
+	Command string
+}
+
+// Pipeline is a command line split into stages at "|". The split is
+// textual, like the command line of tp, so "||" yields an empty stage.
+type Pipeline struct {
+	Stages []Stage
+}
+
+// Parse splits text into stages, trimming the spaces around each command.
+func Parse(text string) Pipeline {
+	var p Pipeline
+	for _, s := range strings.Split(text, "|") {
+		p.Stages = append(p.Stages, Stage{Command: strings.TrimSpace(s)})
+	}
+	return p
+}
+
+// Commands returns the commands of the stages.
+func (p Pipeline) Commands() []string {
+	commands := make([]string, len(p.Stages))
+	for i, s := range p.Stages {
+		commands[i] = s.Command
+	}
+	return commands
+}
+
+// String returns the pipeline as a command line.
+func (p Pipeline) String() string {
+	return strings.Join(p.Commands(), " | ")
+}
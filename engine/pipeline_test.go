@@ -0,0 +1,25 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		input  string
+		result []string
+		text   string
+	}{
+		{input: "ls", result: []string{"ls"}, text: "ls"},
+		{input: "ls | grep a |wc -l", result: []string{"ls", "grep a", "wc -l"}, text: "ls | grep a | wc -l"},
+		{input: "ls |", result: []string{"ls", ""}, text: "ls | "},
+	}
+	for _, tc := range cases {
+		p := Parse(tc.input)
+		result := strings.Join(p.Commands(), ",")
+		if result != strings.Join(tc.result, ",") || p.String() != tc.text {
+			t.Errorf("\nresult:   %q %q\nexpected: %q %q", p.Commands(), p.String(), tc.result, tc.text)
+		}
+	}
+}
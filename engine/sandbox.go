@@ -0,0 +1,37 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+)
+
+// Sandbox creates commands restricted to read-only access to the file
+// system.
+type Sandbox interface {
+	// Name returns the name of the sandbox mechanism.
+	Name() string
+	// Check returns an error if the sandbox isn't available. Commands are
+	// only sandboxed after a successful check.
+	Check() error
+	// Command returns a command running text with shell -c in the sandbox.
+	Command(ctx context.Context, shell, text string) *exec.Cmd
+}
+
+var errNoSandbox = errors.New("no sandbox available on this platform")
+
+// None runs commands without a sandbox. Its check always fails, so that it
+// isn't used by mistake where a sandbox is required.
+type None struct{}
+
+func (None) Name() string {
+	return "none"
+}
+
+func (None) Check() error {
+	return errNoSandbox
+}
+
+func (None) Command(ctx context.Context, shell, text string) *exec.Cmd {
+	return exec.CommandContext(ctx, shell, "-c", text)
+}
@@ -1,6 +1,6 @@
 //go:build darwin
 
-package main
+package engine
 
 import (
 	"context"
@@ -23,7 +23,19 @@ const seatbeltProfile = `(version 1)
 (allow sysctl-read)
 (allow mach*)`
 
-func checkSandbox() error {
+// Seatbelt runs commands with sandbox-exec.
+type Seatbelt struct{}
+
+// DefaultSandbox returns the sandbox of the platform.
+func DefaultSandbox() Sandbox {
+	return Seatbelt{}
+}
+
+func (Seatbelt) Name() string {
+	return "seatbelt"
+}
+
+func (Seatbelt) Check() error {
 	_, err := exec.LookPath("sandbox-exec")
 	if err != nil {
 		return fmt.Errorf("sandbox-exec not found: %w", err)
@@ -31,14 +43,10 @@ func checkSandbox() error {
 	return nil
 }
 
-func sandboxedCommand(shell, text string) *exec.Cmd {
-	return exec.Command("sandbox-exec", "-p", seatbeltProfile, shell, "-c", text)
-}
-
-func sandboxedCommandContext(ctx context.Context, shell, text string) *exec.Cmd {
+func (Seatbelt) Command(ctx context.Context, shell, text string) *exec.Cmd {
 	return exec.CommandContext(ctx, "sandbox-exec", "-p", seatbeltProfile, shell, "-c", text)
 }
 
-// runInSandbox is a no-op on Darwin; sandboxing is applied per-command
+// RunInSandbox is a no-op on Darwin; sandboxing is applied per-command
 // via sandbox-exec rather than through self-re-execution.
-func runInSandbox() {}
+func RunInSandbox() {}
@@ -1,6 +1,6 @@
 //go:build linux
 
-package main
+package engine
 
 import (
 	"context"
@@ -16,11 +16,24 @@ import (
 
 const sandboxEnvVar = "TP_SANDBOX_EXEC"
 
-var selfExe string // set by checkSandbox()
+// Landlock runs commands by re-executing the program, which applies
+// Landlock in RunInSandbox before it execve's the shell.
+type Landlock struct {
+	exe string // set by Check
+}
+
+// DefaultSandbox returns the sandbox of the platform.
+func DefaultSandbox() Sandbox {
+	return &Landlock{}
+}
 
-// checkSandbox verifies that Landlock V3+ is supported by the running kernel
-// and that the tp executable path is resolvable for self-re-execution.
-func checkSandbox() error {
+func (l *Landlock) Name() string {
+	return "landlock"
+}
+
+// Check verifies that Landlock V3+ is supported by the running kernel
+// and that the executable path is resolvable for self-re-execution.
+func (l *Landlock) Check() error {
 	abi, err := llsyscall.LandlockGetABIVersion()
 	if err != nil {
 		return fmt.Errorf("Landlock is not supported by this kernel: %w", err)
@@ -33,17 +46,26 @@ func checkSandbox() error {
 	if err != nil {
 		return fmt.Errorf("cannot determine executable path: %w", err)
 	}
-	selfExe = exe
+	l.exe = exe
 	return nil
 }
 
-// runInSandbox checks if the current process is the re-executed sandbox
+func (l *Landlock) Command(ctx context.Context, shell, text string) *exec.Cmd {
+	if l.exe == "" {
+		return exec.CommandContext(ctx, shell, "-c", text)
+	}
+	cmd := exec.CommandContext(ctx, l.exe, shell, "-c", text)
+	cmd.Env = append(filteredEnv(), sandboxEnvVar+"=1")
+	return cmd
+}
+
+// RunInSandbox checks if the current process is the re-executed sandbox
 // worker. If so, it applies Landlock, then execve's the shell command
 // encoded in the process arguments, replacing the process image.
 //
 // This must be called at the very start of main(), before flag.Parse(),
 // because it inspects os.Args directly.
-func runInSandbox() {
+func RunInSandbox() {
 	if os.Getenv(sandboxEnvVar) != "1" {
 		return
 	}
@@ -79,21 +101,3 @@ func filteredEnv() []string {
 	}
 	return result
 }
-
-func sandboxedCommand(shell, text string) *exec.Cmd {
-	if selfExe == "" {
-		return exec.Command(shell, "-c", text)
-	}
-	cmd := exec.Command(selfExe, shell, "-c", text)
-	cmd.Env = append(filteredEnv(), sandboxEnvVar+"=1")
-	return cmd
-}
-
-func sandboxedCommandContext(ctx context.Context, shell, text string) *exec.Cmd {
-	if selfExe == "" {
-		return exec.CommandContext(ctx, shell, "-c", text)
-	}
-	cmd := exec.CommandContext(ctx, selfExe, shell, "-c", text)
-	cmd.Env = append(filteredEnv(), sandboxEnvVar+"=1")
-	return cmd
-}
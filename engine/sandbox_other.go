@@ -0,0 +1,11 @@
+//go:build !darwin && !linux
+
+package engine
+
+// DefaultSandbox returns the sandbox of the platform.
+func DefaultSandbox() Sandbox {
+	return None{}
+}
+
+// RunInSandbox is a no-op where no sandbox is available.
+func RunInSandbox() {}
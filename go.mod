@@ -8,6 +8,7 @@ require (
 	github.com/landlock-lsm/go-landlock v0.7.0
 	github.com/mattn/go-isatty v0.0.20
 	github.com/rivo/tview v0.0.0-20240818110301-fd649dbf1223
+	github.com/rivo/uniseg v0.4.7
 	golang.org/x/sys v0.42.0
 	golang.org/x/term v0.41.0
 	golang.org/x/text v0.17.0
@@ -17,6 +18,5 @@ require (
 	github.com/gdamore/encoding v1.0.1 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-runewidth v0.0.16 // indirect
-	github.com/rivo/uniseg v0.4.7 // indirect
 	kernel.org/pub/linux/libs/security/libcap/psx v1.2.77 // indirect
 )
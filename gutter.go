@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+func (v *viewPane) toggleLineNumbers() {
+	v.lineNumbers = !v.lineNumbers
+}
+
+func (v *viewPane) toggleRuler() {
+	v.ruler = !v.ruler
+}
+
+// drawText draws the text with the line number gutter and the column ruler
+// in the border padding of the pane. Lines are never wrapped, so a row of
+// the pane is a line of the text and the numbers follow the scroll offset,
+// counting the lines dropped before the text.
+func (v *viewPane) drawText(screen tcell.Screen) {
+	lines := v.lineCount()
+	gutter, ruler := 0, 0
+	if v.lineNumbers {
+		gutter = len(strconv.Itoa(max(v.lineOffset+lines, 1))) + 1
+	}
+	if v.ruler {
+		ruler = 1
+	}
+	v.SetBorderPadding(ruler, 0, gutter, 0)
+	v.TextView.Draw(screen)
+	v.drawSelection(screen)
+
+	x, y, width, height := v.GetInnerRect()
+	row, column := v.GetScrollOffset()
+	c := color(currentTheme.Border)
+	if v.lineNumbers {
+		for i := 0; i < height && row+i < lines; i++ {
+			tview.Print(screen, strconv.Itoa(v.lineOffset+row+i+1), x-gutter, y+i, gutter-1, tview.AlignRight, c)
+		}
+	}
+	if v.ruler {
+		tview.Print(screen, rulerText(column, width), x, y-1, width, tview.AlignLeft, c)
+	}
+}
+
+// lineCount returns the number of lines of the text. GetOriginalLineCount
+// doesn't lock the TextView, which a running command may be writing.
+func (v *viewPane) lineCount() int {
+	v.TextView.Lock()
+	defer v.TextView.Unlock()
+	return v.GetOriginalLineCount()
+}
+
+// rulerText returns a ruler of width columns following offset columns,
+// with a "+" every 5 columns and the column number every 10 columns.
+func rulerText(offset, width int) string {
+	r := make([]byte, max(width, 0))
+	for i := range r {
+		r[i] = '.'
+		if (offset+i+1)%5 == 0 {
+			r[i] = '+'
+		}
+	}
+	for i := range r {
+		if n := offset + i + 1; n%10 == 0 {
+			s := strconv.Itoa(n)
+			start := i - len(s) + 1
+			if start < 0 {
+				s, start = s[-start:], 0
+			}
+			copy(r[start:], s)
+		}
+	}
+	return string(r)
+}
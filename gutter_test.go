@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestRulerText(t *testing.T) {
+	cases := []struct {
+		offset int
+		width  int
+		result string
+	}{
+		{offset: 0, width: 12, result: "....+...10.."},
+		{offset: 8, width: 5, result: "10..."},
+		{offset: 9, width: 5, result: "0...."},
+		{offset: 95, width: 6, result: "..100."},
+		{offset: 0, width: 0, result: ""},
+	}
+	for _, tc := range cases {
+		result := rulerText(tc.offset, tc.width)
+		if result != tc.result {
+			t.Errorf("\nresult:   %q\nexpected: %q", result, tc.result)
+		}
+	}
+}
+
+func TestGutter(t *testing.T) {
+	useTheme(t, "monochrome")
+	screen := tcell.NewSimulationScreen("")
+	screen.Init()
+	screen.SetSize(20, 6)
+
+	v := newViewPane("stdin")
+	v.SetRect(0, 0, 20, 6)
+	var lines []string
+	for _, l := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"} {
+		lines = append(lines, strings.Repeat(l, 3))
+	}
+	v.SetText(strings.Join(lines, "\n"))
+	v.toggleLineNumbers()
+	v.toggleRuler()
+	v.ScrollTo(8, 0)
+	v.Draw(screen)
+	screen.Show()
+
+	expected := []string{"   ....+...10....+", " 9 iii", "10 jjj", "11 kkk"}
+	for i, line := range expected {
+		if result := screenLine(screen, i+1); result != line {
+			t.Errorf("\nresult:   %q\nexpected: %q", result, line)
+		}
+	}
+}
+
+func TestGutterLineOffset(t *testing.T) {
+	useTheme(t, "monochrome")
+	screen := tcell.NewSimulationScreen("")
+	screen.Init()
+	screen.SetSize(20, 4)
+
+	// The lines of a stream dropped before the text are counted.
+	v := newViewPane("stdin")
+	v.SetRect(0, 0, 20, 4)
+	v.SetText("x\ny")
+	v.lineOffset = 99
+	v.toggleLineNumbers()
+	v.Draw(screen)
+	screen.Show()
+
+	expected := []string{"100 x", "101 y"}
+	for i, line := range expected {
+		if result := screenLine(screen, i+1); result != line {
+			t.Errorf("\nresult:   %q\nexpected: %q", result, line)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rivo/tview"
+	"golang.org/x/text/transform"
+)
+
+type hexMode int
+
+const (
+	hexAuto hexMode = iota
+	hexOn
+	hexOff
+)
+
+const hexLineSize = 16
+
+// scanBinary reports whether b contains invalid UTF-8, or a NUL byte if nul
+// is set. It also returns the number of bytes checked, which is less than
+// len(b) if b ends with an incomplete rune.
+func scanBinary(b []byte, nul bool) (bool, int) {
+	for i := 0; i < len(b); {
+		r, size := utf8.DecodeRune(b[i:])
+		if r == utf8.RuneError && size == 1 {
+			if !utf8.FullRune(b[i:]) {
+				return false, i
+			}
+			return true, i
+		}
+		if r == 0 && nul {
+			return true, i
+		}
+		i += size
+	}
+	return false, len(b)
+}
+
+// hexLine formats up to 16 bytes at offset like `hexdump -C`.
+func hexLine(offset int, b []byte) string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "%08x  ", offset)
+	for i := 0; i < hexLineSize; i++ {
+		if i < len(b) {
+			fmt.Fprintf(&s, "%02x ", b[i])
+		} else {
+			s.WriteString("   ")
+		}
+		if i == hexLineSize/2-1 {
+			s.WriteByte(' ')
+		}
+	}
+	s.WriteString(" |")
+	for _, c := range b {
+		if c < ' ' || c > '~' {
+			c = '.'
+		}
+		s.WriteByte(c)
+	}
+	s.WriteString("|\n")
+	return s.String()
+}
+
+// hexWriter writes a hexdump of the written bytes to w.
+type hexWriter struct {
+	w      io.WriteCloser
+	offset int
+	buf    []byte
+}
+
+func (h *hexWriter) Write(p []byte) (int, error) {
+	h.buf = append(h.buf, p...)
+	for len(h.buf) >= hexLineSize {
+		if err := h.writeLine(h.buf[:hexLineSize]); err != nil {
+			return 0, err
+		}
+		h.buf = h.buf[hexLineSize:]
+	}
+	return len(p), nil
+}
+
+func (h *hexWriter) writeLine(b []byte) error {
+	_, err := io.WriteString(h.w, tview.Escape(hexLine(h.offset, b)))
+	h.offset += len(b)
+	return err
+}
+
+func (h *hexWriter) Close() error {
+	if len(h.buf) > 0 {
+		h.writeLine(h.buf)
+		h.buf = nil
+	}
+	return h.w.Close()
+}
+
+// paneWriter displays output in a viewPane and writes it to data. The output
+// is shown as text until it turns out to be binary, then the pane is
+// redrawn as a hexdump, unless the pane's hex mode forces one of them.
+// While the input encoding is decoded, only NUL bytes make it binary, and
+// while whitespace is visualized, NUL bytes don't. The settings are copied
+// when the writer is created, as they may be toggled while it writes.
+// Nothing is displayed once ctx is done, as the pane is reset for the next
+// command meanwhile.
+type paneWriter struct {
+	ctx        context.Context
+	v          *viewPane
+	data       io.Writer
+	mode       hexMode
+	decoding   bool
+	whitespace bool
+	binary     bool
+	pending    []byte // an incomplete rune at the end of the output
+	head       []byte // the output fitting into the pane as a hexdump
+	headLimit  int
+	w          io.WriteCloser
+}
+
+func newPaneWriter(ctx context.Context, v *viewPane, data io.Writer) *paneWriter {
+	v.binary.Store(false)
+	pw := &paneWriter{
+		ctx:       ctx,
+		v:         v,
+		data:      data,
+		headLimit: max(getTerminalHeight()-3, scrollback) * hexLineSize,
+	}
+	v.syncUpdate(func() {
+		pw.mode, pw.whitespace = v.hexMode, v.whitespace
+		pw.decoding = inputEncoding != nil && decodeInput
+	})
+	pw.w = newTextWriter(v, pw.whitespace, pw.decoding)
+	if pw.mode == hexOn {
+		pw.w = &hexWriter{w: transform.NewWriter(v, newTextLineTransformer())}
+	}
+	return pw
+}
+
+func (pw *paneWriter) Write(p []byte) (int, error) {
+	if _, err := pw.data.Write(p); err != nil {
+		return 0, err
+	}
+	if n := pw.headLimit - len(pw.head); n > 0 {
+		pw.head = append(pw.head, p[:min(n, len(p))]...)
+	}
+	if pw.binary {
+		return len(p), pw.display(p)
+	}
+
+	b := append(pw.pending, p...)
+	binary, n := pw.scan(b)
+	pw.pending = bytes.Clone(b[n:])
+	if !binary {
+		return len(p), pw.display(p)
+	}
+
+	pw.binary = true
+	pw.v.binary.Store(true)
+	if pw.mode != hexAuto {
+		return len(p), pw.display(p)
+	}
+	pw.w = &hexWriter{w: transform.NewWriter(pw.v, newTextLineTransformer())}
+	return len(p), pw.show(func() error {
+		pw.v.Clear()
+		_, err := pw.w.Write(pw.head)
+		return err
+	})
+}
+
+func (pw *paneWriter) display(b []byte) error {
+	return pw.show(func() error {
+		_, err := pw.w.Write(b)
+		return err
+	})
+}
+
+// show runs fn writing to the pane unless ctx is done. reset cancels the
+// context under the same lock.
+func (pw *paneWriter) show(fn func() error) error {
+	var err error
+	pw.v.syncUpdate(func() {
+		if pw.ctx.Err() == nil {
+			err = fn()
+		}
+	})
+	return err
+}
+
+func (pw *paneWriter) scan(b []byte) (bool, int) {
+	nul := !pw.whitespace
+	if pw.decoding {
+		return nul && bytes.IndexByte(b, 0) != -1, len(b)
+	}
+	return scanBinary(b, nul)
+}
+
+func (pw *paneWriter) Close() error {
+	return pw.show(pw.w.Close)
+}
+
+func (v *viewPane) cycleHexMode() {
+	v.syncUpdate(func() {
+		v.hexMode = (v.hexMode + 1) % 3
+	})
+}
+
+// hexStatus returns the detected type and how it is rendered for the title
+// of the pane.
+func (v *viewPane) hexStatus() string {
+	binary := v.binary.Load()
+	if !binary && v.hexMode == hexAuto {
+		return ""
+	}
+
+	detected, rendering := "text", "raw"
+	if binary {
+		detected = "binary"
+	}
+	if v.hexMode == hexOn || (v.hexMode == hexAuto && binary) {
+		rendering = "hexdump"
+	}
+	return " (" + detected + ", " + rendering + ")"
+}
+
+// redraw shows content in the pane again with the current hex mode.
+func (v *viewPane) redraw(content []byte) {
+	v.clearSearch()
+	v.selection = nil
+	v.Clear()
+	w := newPaneWriter(context.Background(), v, new(bytes.Buffer))
+	w.Write(content)
+	w.Close()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestScanBinary(t *testing.T) {
+	cases := []struct {
+		input   string
+		binary  bool
+		checked int
+	}{
+		{input: "foo\n", binary: false, checked: 4},
+		{input: "\x1b[31mあ\x1b[0m", binary: false, checked: 12},
+		{input: "foo\x00", binary: true, checked: 3},
+		{input: "\xff\xfe", binary: true, checked: 0},
+		{input: "a\xe3\x81", binary: false, checked: 1},
+	}
+	for _, tc := range cases {
+		binary, checked := scanBinary([]byte(tc.input), true)
+		if binary != tc.binary || checked != tc.checked {
+			t.Errorf("\nresult:   %v %d\nexpected: %v %d", binary, checked, tc.binary, tc.checked)
+		}
+	}
+}
+
+func TestHexLine(t *testing.T) {
+	cases := []struct {
+		offset int
+		input  string
+		result string
+	}{
+		{
+			offset: 0,
+			input:  "0123456789abcdef",
+			result: "00000000  30 31 32 33 34 35 36 37  38 39 61 62 63 64 65 66  |0123456789abcdef|\n",
+		},
+		{
+			offset: 16,
+			input:  "[a]\x00\n",
+			result: "00000010  5b 61 5d 00 0a                                    |[a]..|\n",
+		},
+	}
+	for _, tc := range cases {
+		result := hexLine(tc.offset, []byte(tc.input))
+		if result != tc.result {
+			t.Errorf("\nresult:   %q\nexpected: %q", result, tc.result)
+		}
+	}
+}
+
+func TestHexMode(t *testing.T) {
+	shell = "sh"
+	getTerminalHeight = func() int {
+		return 6
+	}
+
+	cases := []struct {
+		mode   hexMode
+		cmd    string
+		result string
+		status string
+	}{
+		{mode: hexAuto, cmd: "printf 'ab'", result: "ab", status: ""},
+		{mode: hexAuto, cmd: "printf 'a'; sleep 0.1; printf '\\000b'", result: "00000000  61 00 62                                          |a.b|\n", status: " (binary, hexdump)"},
+		{mode: hexOn, cmd: "printf 'ab'", result: "00000000  61 62                                             |ab|\n", status: " (text, hexdump)"},
+		{mode: hexOff, cmd: "printf 'a\\000'", result: "a\x00", status: " (binary, raw)"},
+	}
+	for _, tc := range cases {
+		so := newStdoutViewPane()
+		so.hexMode = tc.mode
+		so.execCommand(context.Background(), tc.cmd, storeBytes(nil))
+		if so.GetText(true) != tc.result || so.hexStatus() != tc.status {
+			t.Errorf("\nresult:   %q %q\nexpected: %q %q", so.GetText(true), so.hexStatus(), tc.result, tc.status)
+		}
+	}
+}
+
+func TestHexStatusWhileRunning(t *testing.T) {
+	tp, release := startPreview(t, "a\x00\n")
+	queueUpdate(t, tp, func() {
+		tp.stdinPane.redraw(stdinData.bytes())
+		tp.stdinPane.SetTitle(tp.stdinPane.title())
+	})
+	release()
+	if status := tp.stdoutPane.hexStatus(); status == "" {
+		t.Errorf("\nresult:   %q\nexpected: binary status", status)
+	}
+}
+
+func TestCycleHexModeWhileRunning(t *testing.T) {
+	tp, release := startPreview(t, "ab\n")
+	queueUpdate(t, tp, func() {
+		tp.redrawViewPane((*viewPane).cycleHexMode)
+	})
+	release()
+	var result string
+	queueUpdate(t, tp, func() {
+		result = tp.stdoutPane.GetText(true)
+	})
+	if expected := "00000000  61 62 0a"; !strings.HasPrefix(result, expected) {
+		t.Errorf("\nresult:   %q\nexpected: %q...", result, expected)
+	}
+}
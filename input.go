@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+var (
+	// inputFiles are read as the input instead of stdin with -f.
+	inputFiles []string
+	// inputCommand is run for the input instead of reading stdin with --input-cmd.
+	inputCommand string
+	// namedInputFlags are the extra inputs given with -i.
+	namedInputFlags []string
+)
+
+// hasInputSource reports whether the input is read from files or a command
+// instead of stdin.
+func hasInputSource() bool {
+	return len(inputFiles) > 0 || inputCommand != ""
+}
+
+// inputName returns the name of the input for the title of the stdin pane.
+func inputName() string {
+	switch {
+	case inputCommand != "":
+		return tview.Escape("$ " + inputCommand)
+	case len(inputFiles) > 0:
+		return tview.Escape(strings.Join(inputFiles, ", "))
+	}
+	return "stdin"
+}
+
+// loadInput reads the input files or runs the input command. A single file
+// is passed to the commands as is, several files are concatenated.
+func loadInput() (*store, error) {
+	if inputCommand != "" {
+		data := newStore(true)
+		stderr := new(bytes.Buffer)
+		cmd := sandboxedCommand(shell, inputCommand)
+		cmd.Stdout = data
+		cmd.Stderr = stderr
+		if err := cmd.Run(); err != nil {
+			data.close()
+			return nil, fmt.Errorf("%s: %w: %s", inputCommand, err, bytes.TrimSpace(stderr.Bytes()))
+		}
+		return data, nil
+	}
+
+	if len(inputFiles) == 1 {
+		return openStore(inputFiles[0])
+	}
+	data := newStore(true)
+	for _, path := range inputFiles {
+		if err := appendFile(data, path); err != nil {
+			data.close()
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func appendFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// checkInputFlags checks that only one source of input is given.
+func checkInputFlags() error {
+	if len(inputFiles) > 0 && inputCommand != "" {
+		return errors.New("-f and --input-cmd can't be used together")
+	}
+	if hasInputSource() && streamFlag {
+		return errors.New("--stream can't be used with -f or --input-cmd")
+	}
+	return nil
+}
+
+// reloadInput reads the input files or runs the input command again in the
+// background, and runs the pipeline on the new input.
+func (t *tui) reloadInput() {
+	t.refreshing = true
+	go func() {
+		data, err := loadInput()
+		t.QueueUpdateDraw(func() {
+			if err != nil {
+				t.refreshing = false
+				t.stdinPane.syncUpdate(func() {
+					t.stdinPane.exitErr = err
+				})
+				t.stdinPane.SetTitle(t.stdinPane.title())
+				return
+			}
+			old := stdinData
+			stdinData = data
+			t.stdinPane.reset()
+			t.updateStdinView()
+			old.close()
+		})
+	}()
+}
+
+// namedInputs are the extra inputs given with -i. Commands read them from
+// the files in $TP_IN1, $TP_IN2 and so on.
+var namedInputs []*namedInput
+
+// namedInput is an input read from a file, or from the output of a command
+// saved to a temp file.
+type namedInput struct {
+	name    string
+	source  string
+	command bool
+	data    *store
+}
+
+// parseNamedInput parses [NAME=]FILE or [NAME=]!COMMAND.
+func parseNamedInput(s string) (*namedInput, error) {
+	in := &namedInput{source: s}
+	if i := strings.IndexByte(s, '='); i > 0 && isInputName(s[:i]) {
+		in.name, in.source = s[:i], s[i+1:]
+	}
+	if strings.HasPrefix(in.source, "!") {
+		in.command = true
+		in.source = in.source[1:]
+	}
+	if in.source == "" {
+		return nil, fmt.Errorf("%s: empty input", s)
+	}
+	if in.name == "" {
+		in.name = in.source
+		if in.command {
+			in.name = "$ " + in.source
+		}
+	}
+	return in, nil
+}
+
+func isInputName(s string) bool {
+	for _, r := range s {
+		if r != '_' && !('a' <= r && r <= 'z') && !('A' <= r && r <= 'Z') && !('0' <= r && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// load opens the file, or runs the command into a temp file which is removed
+// on close.
+func (in *namedInput) load() error {
+	if !in.command {
+		path, err := filepath.Abs(in.source)
+		if err != nil {
+			return err
+		}
+		in.data, err = openStore(path)
+		return err
+	}
+
+	f, err := os.CreateTemp("", "tp-*")
+	if err != nil {
+		return err
+	}
+	stderr := new(bytes.Buffer)
+	cmd := sandboxedCommand(shell, in.source)
+	cmd.Stdout = f
+	cmd.Stderr = stderr
+	err = cmd.Run()
+	f.Close()
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("%s: %w: %s", in.source, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	in.data, err = openStore(f.Name())
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	in.data.keep = false
+	return nil
+}
+
+// path returns the file commands read the input from.
+func (in *namedInput) path() string {
+	return in.data.file.Name()
+}
+
+// title returns the title of the stdin pane showing the i-th input.
+func (in *namedInput) title(i int) string {
+	return tview.Escape(fmt.Sprintf("$TP_IN%d: %s", i, in.name))
+}
+
+// loadNamedInputs loads the inputs given with -i, and exports their paths to
+// the commands as $TP_IN1, $TP_IN2, ... and $TP_IN_NAME for the named ones.
+func loadNamedInputs(args []string) error {
+	for i, arg := range args {
+		in, err := parseNamedInput(arg)
+		if err != nil {
+			return err
+		}
+		if err := in.load(); err != nil {
+			return err
+		}
+		namedInputs = append(namedInputs, in)
+		os.Setenv(fmt.Sprintf("TP_IN%d", i+1), in.path())
+		if name, _, ok := strings.Cut(arg, "="); ok && name == in.name {
+			os.Setenv("TP_IN_"+strings.ToUpper(name), in.path())
+		}
+	}
+	return nil
+}
+
+func closeNamedInputs() {
+	for _, in := range namedInputs {
+		in.data.close()
+	}
+}
+
+// shownInput returns the named input displayed in the stdin pane, or nil
+// while it displays the output of the stage.
+func (si *stdinViewPane) shownInput() *namedInput {
+	if si.shown == 0 {
+		return nil
+	}
+	return namedInputs[si.shown-1]
+}
+
+// cycleShownInput switches the stdin pane to the next named input, and back
+// to the output of the stage after the last one. The stage keeps running on
+// the input of the pipeline whichever is displayed.
+func (t *tui) cycleShownInput() {
+	si := t.stdinPane
+	si.shown = (si.shown + 1) % (len(namedInputs) + 1)
+	name := inputName()
+	if in := si.shownInput(); in != nil {
+		name = in.title(si.shown)
+	}
+	si.syncUpdate(func() {
+		si.name = name
+	})
+	si.reset()
+	t.updateStdinView()
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadInput(t *testing.T) {
+	shell = "sh"
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b[1].txt")
+	os.WriteFile(a, []byte("a\n"), 0o644)
+	os.WriteFile(b, []byte("b\n"), 0o644)
+	defer func() {
+		inputFiles, inputCommand = nil, ""
+	}()
+
+	cases := []struct {
+		files   []string
+		command string
+		name    string
+		result  string
+		isError bool
+	}{
+		{files: []string{a}, name: a, result: "a\n"},
+		{files: []string{a, b}, name: a + ", " + filepath.Join(dir, "b[1[].txt"), result: "a\nb\n"},
+		{files: []string{filepath.Join(dir, "none")}, isError: true},
+		{command: "printf 'c\\n'", name: "$ printf 'c\\n'", result: "c\n"},
+		{command: "echo d >&2; exit 1", isError: true},
+	}
+	for _, tc := range cases {
+		inputFiles, inputCommand = tc.files, tc.command
+		data, err := loadInput()
+		if (err != nil) != tc.isError {
+			t.Errorf("files: %v, command: %s, error: %v", tc.files, tc.command, err)
+		}
+		if err != nil {
+			continue
+		}
+		r, _ := data.reader()
+		result, _ := io.ReadAll(r)
+		closeReader(r)
+		data.close()
+		if string(result) != tc.result || inputName() != tc.name {
+			t.Errorf("\nresult:   %q %s\nexpected: %q %s", result, inputName(), tc.result, tc.name)
+		}
+	}
+
+	// A single input file is passed to commands as it is, and kept on close.
+	inputFiles, inputCommand = []string{a}, ""
+	data, _ := loadInput()
+	data.close()
+	if _, err := os.Stat(a); err != nil {
+		t.Errorf("input file is removed: %v", err)
+	}
+}
+
+func TestParseNamedInput(t *testing.T) {
+	cases := []struct {
+		arg     string
+		name    string
+		source  string
+		command bool
+		isError bool
+	}{
+		{arg: "a.txt", name: "a.txt", source: "a.txt"},
+		{arg: "users=a.txt", name: "users", source: "a.txt"},
+		{arg: "dir/a=b.txt", name: "dir/a=b.txt", source: "dir/a=b.txt"},
+		{arg: "!ls", name: "$ ls", source: "ls", command: true},
+		{arg: "ids=!cut -f1 a.txt", name: "ids", source: "cut -f1 a.txt", command: true},
+		{arg: "ids=", isError: true},
+	}
+	for _, tc := range cases {
+		in, err := parseNamedInput(tc.arg)
+		if (err != nil) != tc.isError {
+			t.Errorf("arg: %s, error: %v", tc.arg, err)
+		}
+		if err != nil {
+			continue
+		}
+		if in.name != tc.name || in.source != tc.source || in.command != tc.command {
+			t.Errorf("\nresult:   %s %s %v\nexpected: %s %s %v", in.name, in.source, in.command, tc.name, tc.source, tc.command)
+		}
+	}
+}
+
+func TestNamedInputs(t *testing.T) {
+	shell = "sh"
+	getTerminalHeight = func() int {
+		return 6
+	}
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	os.WriteFile(a, []byte("1 a\n2 b\n"), 0o644)
+	defer func() {
+		closeNamedInputs()
+		namedInputs = nil
+		os.Unsetenv("TP_IN1")
+		os.Unsetenv("TP_IN2")
+		os.Unsetenv("TP_IN_IDS")
+	}()
+
+	if err := loadNamedInputs([]string{a, "ids=!printf '1 x\\n2 y\\n'"}); err != nil {
+		t.Fatal(err)
+	}
+	if os.Getenv("TP_IN1") != a || os.Getenv("TP_IN_IDS") != os.Getenv("TP_IN2") {
+		t.Errorf("result: %s %s %s", os.Getenv("TP_IN1"), os.Getenv("TP_IN2"), os.Getenv("TP_IN_IDS"))
+	}
+
+	so := newStdoutViewPane()
+	so.execCommand(context.Background(), `join "$TP_IN1" "$TP_IN2"`, storeBytes(nil))
+	if so.GetText(true) != "1 a x\n2 b y\n" {
+		t.Errorf("result: %q", so.GetText(true))
+	}
+
+	// The stage keeps running on the pipeline's input while a named input
+	// is shown.
+	si := newStdinViewPane()
+	si.shown = 2
+	si.display(si.shownInput().data)
+	si.execCommand(context.Background(), "tr a-z A-Z", storeBytes([]byte("abc\n")))
+	if si.GetText(true) != "1 x\n2 y\n" || string(si.data.bytes()) != "ABC\n" {
+		t.Errorf("result: %q %q", si.GetText(true), si.data.bytes())
+	}
+	si.setStore(storeBytes(nil))
+
+	path := namedInputs[1].path()
+	closeNamedInputs()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("temp file is not removed: %v", err)
+	}
+	if _, err := os.Stat(a); err != nil {
+		t.Errorf("input file is removed: %v", err)
+	}
+}
+
+func TestReloadInputWhileRunning(t *testing.T) {
+	t.Cleanup(func() {
+		inputCommand = ""
+	})
+	tp, release := startPreview(t, "a\n")
+	inputCommand = "exit 1"
+	queueUpdate(t, tp, tp.reloadInput)
+	waitFor(t, tp, exited(tp.stdinPane.viewPane))
+	release()
+}
+
+func TestCycleShownInputWhileRunning(t *testing.T) {
+	shell = "sh"
+	t.Cleanup(func() {
+		closeNamedInputs()
+		namedInputs = nil
+		os.Unsetenv("TP_IN1")
+		os.Unsetenv("TP_IN_IDS")
+	})
+	if err := loadNamedInputs([]string{"ids=!printf '1 x\\n'"}); err != nil {
+		t.Fatal(err)
+	}
+	tp, release := startPreview(t, "a\n")
+	queueUpdate(t, tp, tp.cycleShownInput)
+	waitFor(t, tp, func() bool {
+		return tp.stdinPane.GetTitle() == namedInputs[0].title(1)
+	})
+	release()
+}
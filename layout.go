@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rivo/tview"
+)
+
+type layout int
+
+const (
+	layoutHorizontal layout = iota // stdin and stdout side by side
+	layoutVertical                 // stdin above stdout
+	layoutSingle                   // stdout only
+)
+
+var layoutNames = []string{"horizontal", "vertical", "single"}
+
+const (
+	minSplitRatio  = 10
+	maxSplitRatio  = 90
+	splitRatioStep = 10
+)
+
+func parseLayout(s string) (layout, error) {
+	for i, n := range layoutNames {
+		if n == s {
+			return layout(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown layout %q (available: horizontal, vertical, single)", s)
+}
+
+func (l layout) String() string {
+	return layoutNames[l]
+}
+
+// arrange lays out the view panes according to the current layout,
+// split ratio and zoom state.
+func (t *tui) arrange() {
+	t.viewPanes.Clear()
+
+	if t.zoomed {
+		var p tview.Primitive = t.stdoutPane
+		if t.GetFocus() == t.stdinPane {
+			p = t.stdinPane
+		}
+		t.viewPanes.AddItem(p, 0, 1, false)
+		return
+	}
+
+	switch t.layout {
+	case layoutSingle:
+		t.viewPanes.AddItem(t.stdoutPane, 0, 1, false)
+		return
+	case layoutVertical:
+		t.viewPanes.SetDirection(tview.FlexRow)
+	default:
+		t.viewPanes.SetDirection(tview.FlexColumn)
+	}
+	t.viewPanes.AddItem(t.stdinPane, 0, t.splitRatio, false).
+		AddItem(t.stdoutPane, 0, 100-t.splitRatio, false)
+}
+
+func (t *tui) cycleLayout() {
+	t.layout = (t.layout + 1) % layout(len(layoutNames))
+	if t.layout == layoutSingle && t.GetFocus() == t.stdinPane {
+		t.SetFocus(t.cliPane)
+	}
+	t.arrange()
+}
+
+func (t *tui) toggleZoom() {
+	t.zoomed = !t.zoomed
+	t.arrange()
+}
+
+func (t *tui) resize(delta int) {
+	t.splitRatio = min(max(t.splitRatio+delta, minSplitRatio), maxSplitRatio)
+	t.arrange()
+}
+
+// cycleFocus moves the focus between the cliPane and the visible view panes.
+func (t *tui) cycleFocus(reverse bool) {
+	panes := []tview.Primitive{t.cliPane, t.stdinPane, t.stdoutPane}
+	if t.layout == layoutSingle && !t.zoomed {
+		panes = []tview.Primitive{t.cliPane, t.stdoutPane}
+	}
+
+	i := 0
+	for j, p := range panes {
+		if p == t.GetFocus() {
+			i = j
+		}
+	}
+	if reverse {
+		i = (i + len(panes) - 1) % len(panes)
+	} else {
+		i = (i + 1) % len(panes)
+	}
+	t.SetFocus(panes[i])
+	if t.zoomed {
+		t.arrange()
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseLayout(t *testing.T) {
+	cases := []struct {
+		input   string
+		result  layout
+		isError bool
+	}{
+		{input: "horizontal", result: layoutHorizontal, isError: false},
+		{input: "vertical", result: layoutVertical, isError: false},
+		{input: "single", result: layoutSingle, isError: false},
+		{input: "grid", result: layoutHorizontal, isError: true},
+	}
+	for _, tc := range cases {
+		l, err := parseLayout(tc.input)
+		if (err != nil) != tc.isError {
+			t.Errorf("input: %s, error: %v", tc.input, err)
+		}
+		if l != tc.result {
+			t.Errorf("result: %s, expected: %s", l, tc.result)
+		}
+	}
+}
@@ -7,35 +7,53 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"runtime/debug"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	flag "github.com/cornfeedhobo/pflag"
 	"github.com/gdamore/tcell/v2"
 	"github.com/mattn/go-isatty"
+	"github.com/minefuto/tp/engine"
 	"github.com/rivo/tview"
+	"github.com/rivo/uniseg"
 	"golang.org/x/term"
 	"golang.org/x/text/transform"
 )
 
 const (
 	name             = "tp"
-	maxInputLength   = 200
 	transformBufSize = 4096
 	spinnerInterval  = 100 * time.Millisecond
+	// The cursor probe is a rune of the private use area missing from the
+	// text, from firstCursorProbe to lastCursorProbe.
+	firstCursorProbe = '\uE000'
+	lastCursorProbe  = '\uF8FF'
 )
 
 var version = ""
 
 var (
-	shell       string
-	initCommand string
-	commandFlag bool
-	helpFlag    bool
-	versionFlag bool
-	stdinBytes  []byte
+	shell             string
+	initCommand       string
+	commandFlag       bool
+	viFlag            bool
+	layoutFlag        string
+	themeFlag         string
+	inputEncodingFlag string
+	transcodeFlag     bool
+	streamFlag        bool
+	streamBuffer      int
+	refreshInterval   time.Duration
+	splitRatio        int
+	scrollback        int
+	helpFlag          bool
+	versionFlag       bool
+	spillThresholdMiB int
 )
 
 var getTerminalHeight = func() int {
@@ -48,18 +66,31 @@ type tui struct {
 	cliPane    *cliPane
 	stdinPane  *stdinViewPane
 	stdoutPane *stdoutViewPane
+	root       *tview.Flex
+	viewPanes  *tview.Flex
+	searchBar  *tview.InputField
+	searchPane *viewPane
+	writeBar   *tview.InputField
+	writeFocus tview.Primitive
+	writePath  string
+	result     *acceptResult
+	layout     layout
+	splitRatio int
+	zoomed     bool
+
+	refreshing    bool
+	streamVersion int
+	// streamDropped is the number of lines dropped from stdin with --stream.
+	streamDropped int
 }
 
-func newTui() *tui {
+func newTui(l layout) *tui {
 	cliPane := newCliPane()
 	stdinPane := newStdinViewPane()
 	stdoutPane := newStdoutViewPane()
 
 	flex := tview.NewFlex()
 	viewPanes := tview.NewFlex()
-	viewPanes.SetDirection(tview.FlexColumn).
-		AddItem(stdinPane, 0, 1, false).
-		AddItem(stdoutPane, 0, 1, false)
 
 	flex.SetDirection(tview.FlexRow).
 		AddItem(cliPane, 1, 0, false).
@@ -70,13 +101,184 @@ func newTui() *tui {
 		cliPane:     cliPane,
 		stdinPane:   stdinPane,
 		stdoutPane:  stdoutPane,
+		root:        flex,
+		viewPanes:   viewPanes,
+		searchBar:   newSearchBar(),
+		writeBar:    newWriteBar(),
+		layout:      l,
+		splitRatio:  min(max(splitRatio, minSplitRatio), maxSplitRatio),
 	}
 	t.SetRoot(flex, true).SetFocus(cliPane)
+	t.arrange()
 	t.setAction()
 	return t
 }
 
 func (t *tui) setAction() {
+	t.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyCtrlC {
+			t.acceptCancel()
+			return event
+		}
+		if t.GetFocus() == t.searchBar || t.GetFocus() == t.writeBar {
+			return event
+		}
+		if event.Key() == tcell.KeyRune && event.Modifiers()&tcell.ModAlt != 0 {
+			switch event.Rune() {
+			case 'n':
+				t.targetViewPane().toggleLineNumbers()
+				return nil
+			case 'r':
+				t.targetViewPane().toggleRuler()
+				return nil
+			case 'i':
+				if len(namedInputs) > 0 {
+					t.cycleShownInput()
+				}
+				return nil
+			case 'w':
+				t.openWriteBar()
+				return nil
+			case 'c':
+				t.acceptClipboard()
+				return nil
+			case 'p':
+				t.acceptPrint()
+				return nil
+			case 'y':
+				t.copyPipeline()
+				return nil
+			}
+		}
+
+		switch event.Key() {
+		case tcell.KeyTab, tcell.KeyBacktab:
+			t.cycleFocus(event.Key() == tcell.KeyBacktab)
+			return nil
+		case tcell.KeyEscape:
+			if v := t.focusedViewPane(); v != nil && v.selection != nil {
+				v.toggleSelection()
+				return nil
+			}
+			if v := t.focusedViewPane(); v != nil && v.search != nil {
+				v.clearSearch()
+				v.SetTitle(v.title())
+				return nil
+			}
+			if t.GetFocus() != t.cliPane {
+				t.SetFocus(t.cliPane)
+				if t.zoomed {
+					t.arrange()
+				}
+				return nil
+			}
+		case tcell.KeyCtrlL:
+			t.refresh()
+			return nil
+		case tcell.KeyF2:
+			t.cycleLayout()
+			return nil
+		case tcell.KeyF3:
+			t.toggleZoom()
+			return nil
+		case tcell.KeyF4:
+			t.resize(-splitRatioStep)
+			return nil
+		case tcell.KeyF5:
+			t.resize(splitRatioStep)
+			return nil
+		case tcell.KeyF6:
+			t.stdoutPane.cycleDiff()
+			t.renderStdoutView()
+			return nil
+		case tcell.KeyF7:
+			t.stdoutPane.toggleDiffWords()
+			t.renderStdoutView()
+			return nil
+		case tcell.KeyF8:
+			t.stdoutPane.togglePrevDiff()
+			t.renderStdoutView()
+			return nil
+		case tcell.KeyF9:
+			v := t.targetViewPane()
+			v.toggleStructured()
+			v.SetTitle(v.title())
+			return nil
+		case tcell.KeyF10:
+			t.redrawViewPane((*viewPane).cycleHexMode)
+			return nil
+		case tcell.KeyF11:
+			// Writers copy decodeInput under the lock of their pane.
+			t.stdinPane.syncUpdate(func() {
+				t.stdoutPane.syncUpdate(toggleDecodeInput)
+			})
+			if t.stdinPane.running {
+				t.restartStdinView()
+			} else {
+				t.stdinPane.redraw(t.stdinPane.content)
+				t.stdinPane.SetTitle(t.stdinPane.title())
+			}
+			if t.stdoutPane.running {
+				t.restartStdoutView()
+			} else {
+				t.renderStdoutView()
+			}
+			return nil
+		case tcell.KeyF12:
+			t.redrawViewPane((*viewPane).toggleWhitespace)
+			return nil
+		}
+		return event
+	})
+
+	for _, v := range []*viewPane{t.stdinPane.viewPane, t.stdoutPane.viewPane} {
+		v.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			if v.structured != nil {
+				return event
+			}
+			if v.selection != nil {
+				switch {
+				case event.Key() == tcell.KeyDown, event.Key() == tcell.KeyRune && event.Rune() == 'j':
+					v.moveSelection(1)
+					return nil
+				case event.Key() == tcell.KeyUp, event.Key() == tcell.KeyRune && event.Rune() == 'k':
+					v.moveSelection(-1)
+					return nil
+				}
+			}
+			if event.Key() != tcell.KeyRune {
+				return event
+			}
+			switch event.Rune() {
+			case 'v':
+				v.toggleSelection()
+				return nil
+			case 'y':
+				v.copyPane()
+				return nil
+			case '/':
+				t.openSearch(v)
+				return nil
+			case 'n', 'N':
+				if event.Rune() == 'n' {
+					v.nextMatch(1)
+				} else {
+					v.nextMatch(-1)
+				}
+				v.SetTitle(v.title())
+				return nil
+			}
+			return event
+		})
+	}
+
+	t.searchBar.SetChangedFunc(func(text string) {
+		t.searchPane.setSearch(text)
+		t.searchPane.SetTitle(t.searchPane.title())
+	})
+	t.searchBar.SetDoneFunc(t.closeSearch)
+	t.writeBar.SetDoneFunc(t.doneWriteBar)
+
 	t.stdinPane.SetChangedFunc(func() {
 		t.Draw()
 	})
@@ -86,6 +288,7 @@ func (t *tui) setAction() {
 	})
 
 	t.cliPane.SetChangedFunc(func(text string) {
+		t.cliPane.recordChange()
 		_text := strings.TrimSpace(text)
 		if t.cliPane.trimText == _text {
 			t.cliPane.trimText = _text
@@ -97,30 +300,19 @@ func (t *tui) setAction() {
 	})
 
 	t.cliPane.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyCtrlC:
-			if commandFlag {
-				fmt.Println(initCommand)
-				return event
+		t.cliPane.typing = event.Key() == tcell.KeyRune && event.Modifiers()&tcell.ModAlt == 0 && event.Rune() != ' '
+		if t.cliPane.vi != nil {
+			if t.cliPane.vi.mode == viNormal {
+				t.cliPane.typing = false
 			}
-			return event
-
-		case tcell.KeyEnter:
-			t.stdinPane.cancel()
-			t.stdoutPane.cancel()
-			t.Stop()
-
-			_text := adjustPipe(t.cliPane.prompt) + t.cliPane.GetText()
-			if commandFlag {
-				fmt.Println(_text)
+			if event = t.cliPane.handleVi(event); event == nil {
 				return nil
 			}
+		}
 
-			cmd := sandboxedCommand(shell, _text)
-			cmd.Stdin = bytes.NewReader(stdinBytes)
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
-			cmd.Run()
+		switch event.Key() {
+		case tcell.KeyEnter:
+			t.acceptRun()
 			return nil
 
 		case tcell.KeyBackspace, tcell.KeyBackspace2:
@@ -129,16 +321,35 @@ func (t *tui) setAction() {
 					return event
 				}
 				t.cliPane.setPrompt(t.cliPane.prompt)
+				t.cliPane.record()
 				t.stdinPane.reset()
 				t.updateStdinView()
 				return nil
 			}
 			return event
 
+		case tcell.KeyCtrlO:
+			t.editStage()
+			return nil
+
+		case tcell.KeyCtrlZ, tcell.KeyCtrlY:
+			prompt := t.cliPane.prompt
+			if event.Key() == tcell.KeyCtrlZ {
+				t.cliPane.undo()
+			} else {
+				t.cliPane.redo()
+			}
+			if t.cliPane.prompt != prompt {
+				t.stdinPane.reset()
+				t.updateStdinView()
+			}
+			return nil
+
 		case tcell.KeyRune:
 			switch event.Rune() {
 			case '|':
 				t.cliPane.addPrompt()
+				t.cliPane.record()
 				t.stdinPane.reset()
 				t.updateStdinView()
 				return nil
@@ -148,9 +359,29 @@ func (t *tui) setAction() {
 	})
 }
 
+// focusedViewPane returns the view pane which has focus, or nil.
+func (t *tui) focusedViewPane() *viewPane {
+	switch t.GetFocus() {
+	case t.stdinPane:
+		return t.stdinPane.viewPane
+	case t.stdoutPane:
+		return t.stdoutPane.viewPane
+	}
+	return nil
+}
+
 func (t *tui) start() int {
+	if t.cliPane.initCursor >= 0 {
+		// The input field moves the cursor only once it has been drawn.
+		t.QueueUpdateDraw(func() {
+			t.cliPane.setCursor(t.cliPane.initCursor)
+		})
+	}
 	t.updateStdinView()
 	t.updateStdoutView(t.cliPane.GetText())
+	if inputStream != nil && refreshInterval > 0 {
+		go t.watchStream(refreshInterval)
+	}
 
 	if err := t.Run(); err != nil {
 		t.Stop()
@@ -160,15 +391,26 @@ func (t *tui) start() int {
 }
 
 func (t *tui) updateStdinView() {
-	stdinCtx, stdinCancel := context.WithCancel(t.stdinPane.ctx)
-
-	p := t.cliPane.prompt
+	paneCtx := t.stdinPane.ctx
+	stdinCtx, stdinCancel := context.WithCancel(paneCtx)
+	t.stdinPane.running = true
+
+	p := restoreNewlines(t.cliPane.prompt)
+	shown := t.stdinPane.shownInput()
+	input := stdinData
+	t.stdinPane.lineOffset = 0
+	if p == "" && shown == nil {
+		t.stdinPane.lineOffset = t.streamDropped
+	}
 	go func() {
 		defer stdinCancel()
+		if shown != nil {
+			t.stdinPane.display(shown.data)
+		}
 		if p == "" {
-			t.stdinPane.setData(stdinBytes)
+			t.stdinPane.setData(input)
 		} else {
-			t.stdinPane.execCommand(stdinCtx, p, stdinBytes)
+			t.stdinPane.execCommand(stdinCtx, p, input)
 		}
 	}()
 	go func() {
@@ -182,36 +424,122 @@ func (t *tui) updateStdinView() {
 				t.stdinPane.syncUpdate(func() {
 					t.stdinPane.isLoading = false
 				})
+				data := t.stdinPane.bytes()
+				if shown != nil {
+					data = shown.data.bytes()
+				}
 				t.QueueUpdateDraw(func() {
-					t.stdinPane.SetTitle(t.stdinPane.name)
+					if paneCtx.Err() == nil {
+						t.stdinPane.running = false
+					}
+					t.stdinPane.setContent(data)
+					t.stdinPane.SetTitle(t.stdinPane.title())
+					if t.refreshing && paneCtx.Err() == nil {
+						t.refreshing = false
+						t.stdoutPane.reset()
+						t.updateStdoutView(t.cliPane.GetText())
+					}
 				})
 				return
 			case <-time.After(spinnerInterval):
 				t.QueueUpdateDraw(func() {
-					t.stdinPane.SetTitle(t.stdinPane.name + s())
+					t.stdinPane.SetTitle(t.stdinPane.name + currentTheme.spinnerTag() + s())
 				})
 			}
 		}
 	}()
 }
 
+// targetViewPane returns the focused view pane, or the stdout pane while
+// the command line is focused.
+func (t *tui) targetViewPane() *viewPane {
+	if v := t.focusedViewPane(); v != nil {
+		return v
+	}
+	return t.stdoutPane.viewPane
+}
+
+// redrawViewPane applies fn to the target view pane and redraws it, or
+// runs its command again while it is running.
+func (t *tui) redrawViewPane(fn func(v *viewPane)) {
+	v := t.targetViewPane()
+	fn(v)
+	switch {
+	case v == t.stdoutPane.viewPane && v.running:
+		t.restartStdoutView()
+	case v == t.stdoutPane.viewPane:
+		t.renderStdoutView()
+	case v.running:
+		t.restartStdinView()
+	default:
+		v.redraw(v.content)
+		v.SetTitle(v.title())
+	}
+}
+
+// restartStdinView runs the stage of the stdin pane again, as the running
+// one keeps writing with the settings it started with.
+func (t *tui) restartStdinView() {
+	t.stdinPane.reset()
+	t.updateStdinView()
+}
+
+// restartStdoutView runs the preview again, as the running one keeps
+// writing with the settings it started with.
+func (t *tui) restartStdoutView() {
+	t.stdoutPane.reset()
+	t.updateStdoutView(t.cliPane.GetText())
+}
+
+// renderStdoutView redraws the stdout pane from the last result without
+// running the command again.
+func (t *tui) renderStdoutView() {
+	t.stdoutPane.render()
+	t.stdoutPane.setContent(t.stdoutPane.output())
+	t.stdoutPane.SetTitle(t.stdoutPane.title())
+}
+
 func (t *tui) updateStdoutView(text string) {
+	text = restoreNewlines(text)
 	stdoutCtx, stdoutCancel := context.WithCancel(t.stdoutPane.ctx)
+	t.stdoutPane.running = true
 
 	go func() {
 		defer stdoutCancel()
+		var isLoading bool
 		t.stdinPane.syncUpdate(func() {
+			isLoading = t.stdinPane.isLoading
+		})
+		t.QueueUpdateDraw(func() {
+			if isLoading && stdoutCtx.Err() == nil {
+				t.stdoutPane.running = false
+			}
+			if isLoading {
+				t.stdoutPane.SetTitle("no preview")
+			} else {
+				t.stdoutPane.SetTitle(t.stdoutPane.name)
+			}
+		})
+		if isLoading {
+			return
+		}
+
+		t.stdoutPane.execCommand(stdoutCtx, text, t.stdinPane)
+		select {
+		case <-stdoutCtx.Done():
+		default:
+			output := t.stdoutPane.output()
 			t.QueueUpdateDraw(func() {
-				if t.stdinPane.isLoading {
-					t.stdoutPane.SetTitle("no preview")
-				} else {
-					t.stdoutPane.SetTitle("stdout/stderr")
+				if stdoutCtx.Err() == nil {
+					t.stdoutPane.running = false
+				}
+				if t.stdoutPane.diffing() {
+					t.stdoutPane.renderDiff()
 				}
+				t.stdoutPane.setContent(output)
+				t.stdoutPane.SetTitle(t.stdoutPane.title())
 			})
-			if !t.stdinPane.isLoading {
-				t.stdoutPane.execCommand(stdoutCtx, text, t.stdinPane.data)
-			}
-		})
+		}
 	}()
 }
 
@@ -227,30 +555,104 @@ func spinner() func() string {
 
 type cliPane struct {
 	*tview.InputField
-	symbol   string
-	prompt   string
-	trimText string
-	mu       sync.Mutex
+	symbol string
+	prompt string
+	// initCursor is the cursor position given by --cursor in the text, and
+	// rest the stages after the text, which are kept as they are.
+	initCursor int
+	rest       string
+	trimText   string
+	vi         *viEditor
+	cursorPos  int
+	probe      rune
+
+	undoStack  []editState
+	redoStack  []editState
+	last       editState
+	typing     bool
+	lastTyping bool
+	restoring  bool
+	// handling is set while the input field handles a key, and changed
+	// when the text has been changed meanwhile.
+	handling bool
+	changed  bool
+
+	mu sync.Mutex
 }
 
 func newCliPane() *cliPane {
 	inputField := tview.NewInputField()
-	inputField.SetAcceptanceFunc(tview.InputFieldMaxLength(maxInputLength)).
-		SetFieldWidth(0)
-
-	symbol := "| "
-	if bytes.Equal(stdinBytes, []byte("")) {
-		symbol = "> "
-	}
+	inputField.SetFieldWidth(0)
 
 	c := &cliPane{
 		InputField: inputField,
-		symbol:     symbol,
+		symbol:     currentTheme.promptSymbol(stdinData.len() != 0 || inputStream != nil),
+	}
+	if viFlag {
+		c.vi = newViEditor()
 	}
-	c.setPrompt(initCommand)
+	c.SetAcceptanceFunc(c.accept)
+	command, cursor, rest := cursorStage(showNewlines(initCommand), cursorFlag)
+	c.initCursor, c.rest = cursor, rest
+	c.setPrompt(command)
+	c.last = c.state()
 	return c
 }
 
+func (c *cliPane) accept(text string, ch rune) bool {
+	if c.probe != 0 && ch == c.probe {
+		c.cursorPos = utf8.RuneCountInString(text[:strings.IndexRune(text, c.probe)])
+		return false
+	}
+	return true
+}
+
+// sendKeys feeds key events to the underlying input field,
+// bypassing the input capture.
+func (c *cliPane) sendKeys(events ...*tcell.EventKey) {
+	capture := c.GetInputCapture()
+	c.SetInputCapture(nil)
+	defer c.SetInputCapture(capture)
+
+	handler := c.InputField.InputHandler()
+	for _, event := range events {
+		handler(event, func(tview.Primitive) {})
+	}
+}
+
+// cursor returns the cursor position in runes. The input field doesn't
+// expose it, so a probe rune missing from the text is offered to the
+// acceptance func and rejected.
+func (c *cliPane) cursor() int {
+	text := c.GetText()
+	c.probe = firstCursorProbe
+	for strings.ContainsRune(text, c.probe) && c.probe < lastCursorProbe {
+		c.probe++
+	}
+	c.sendKeys(tcell.NewEventKey(tcell.KeyRune, c.probe, tcell.ModNone))
+	c.probe = 0
+	return c.cursorPos
+}
+
+func (c *cliPane) setCursor(pos int) {
+	text := []rune(c.GetText())
+	pos = min(max(pos, 0), len(text))
+
+	events := []*tcell.EventKey{tcell.NewEventKey(tcell.KeyHome, 0, tcell.ModNone)}
+	for range uniseg.GraphemeClusterCount(string(text[:pos])) {
+		events = append(events, tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModNone))
+	}
+	c.sendKeys(events...)
+}
+
+func (c *cliPane) updateLabel() {
+	label := c.symbol + adjustPipe(c.prompt)
+	if c.vi != nil {
+		label = c.vi.indicator() + label
+	}
+	c.SetLabel(label)
+}
+
 func (c *cliPane) syncUpdate(fn func()) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -260,19 +662,19 @@ func (c *cliPane) syncUpdate(fn func()) {
 func (c *cliPane) setPrompt(text string) {
 	if strings.Contains(text, "|") {
 		c.prompt = text[:strings.LastIndex(text, "|")]
-		c.SetLabel(c.symbol + adjustPipe(c.prompt))
+		c.updateLabel()
 		c.SetText(text[strings.LastIndex(text, "|")+1:])
 		return
 	}
-	c.SetLabel(c.symbol)
-	c.SetText(text)
 	c.prompt = ""
+	c.updateLabel()
+	c.SetText(text)
 }
 
 func (c *cliPane) addPrompt() {
 	c.prompt = adjustPipe(c.prompt) + c.GetText()
-	c.SetLabel(c.symbol + adjustPipe(c.prompt)).
-		SetText("")
+	c.updateLabel()
+	c.SetText("")
 }
 
 func adjustPipe(text string) string {
@@ -284,17 +686,38 @@ func adjustPipe(text string) string {
 
 type viewPane struct {
 	*tview.TextView
-	name   string
-	ctx    context.Context
-	cancel context.CancelFunc
+	name    string
+	ctx     context.Context
+	cancel  context.CancelFunc
+	exitErr error
+	search  *search
+	hexMode hexMode
+	// binary is set by the paneWriter while the title is drawn.
+	binary atomic.Bool
 	mu     sync.Mutex
+	// running is set on the UI goroutine until the result of the command
+	// of the pane is displayed.
+	running bool
+
+	whitespace  bool
+	lineNumbers bool
+	ruler       bool
+	// lineOffset is the number of lines before the text, which were dropped
+	// from the input.
+	lineOffset int
+
+	content      []byte
+	structuredOn bool
+	structured   tview.Primitive
+	kind         string
+	selection    *selection
 }
 
 func newViewPane(name string) *viewPane {
 	textView := tview.NewTextView()
 	textView.SetWrap(false).
 		SetDynamicColors(true).
-		SetScrollable(false).
+		SetScrollable(true).
 		SetTitleAlign(tview.AlignLeft).
 		SetTitle(name).
 		SetBorder(true)
@@ -307,6 +730,7 @@ func newViewPane(name string) *viewPane {
 		ctx:      ctx,
 		cancel:   cancel,
 	}
+	currentTheme.setFocusBorder(v)
 	return v
 }
 
@@ -316,65 +740,140 @@ func (v *viewPane) syncUpdate(fn func()) {
 	fn()
 }
 
+// title returns the pane name with the exit status of the last command.
+func (v *viewPane) title() string {
+	var title string
+	v.syncUpdate(func() {
+		title = v.name
+		if v.exitErr != nil {
+			title += " " + currentTheme.errorTag() + "(" + tview.Escape(v.exitErr.Error()) + ")[-::-]"
+		}
+	})
+	if v.search != nil {
+		title += v.search.status()
+	}
+	return title + encodingStatus() + v.hexStatus() + v.whitespaceStatus() + v.structuredStatus()
+}
+
 func (v *viewPane) reset() {
+	v.content, v.structured, v.kind = nil, nil, ""
+	v.selection = nil
+	v.search = nil
+	// The writer of a running command stops displaying once it's canceled.
+	v.syncUpdate(v.cancel)
+	v.SetRegions(false)
 	v.Clear()
-	v.cancel()
+	v.running = false
 	v.ctx, v.cancel = context.WithCancel(context.Background())
 }
 
 type stdinViewPane struct {
 	*viewPane
-	data      []byte
+	// dataMu guards data, which a stdout command opens while the stdin
+	// command may replace it.
+	dataMu    sync.RWMutex
+	data      *store
 	isLoading bool
+	// shown is the number of the named input displayed instead of the
+	// output of the stage, or 0.
+	shown int
 }
 
 func newStdinViewPane() *stdinViewPane {
-	v := newViewPane("stdin")
+	v := newViewPane(inputName())
 	si := &stdinViewPane{
 		viewPane:  v,
-		data:      []byte(""),
+		data:      storeBytes(nil),
 		isLoading: false,
 	}
 	return si
 }
 
-func (si *stdinViewPane) setData(inputBytes []byte) {
-	tt := newTextLineTransformer()
-	w := transform.NewWriter(tview.ANSIWriter(si), tt)
+// setStore replaces the data of the pane and removes the temp file of the
+// old one once no stdout command is reading it.
+func (si *stdinViewPane) setStore(data *store) {
+	si.dataMu.Lock()
+	defer si.dataMu.Unlock()
+	if si.data != stdinData {
+		si.data.close()
+	}
+	si.data = data
+}
 
-	si.syncUpdate(func() {
-		si.data = make([]byte, len(inputBytes))
-		copy(si.data, inputBytes)
-	})
-	io.Copy(w, bytes.NewReader(inputBytes))
+// bytes returns the head of the data of the pane.
+func (si *stdinViewPane) bytes() []byte {
+	si.dataMu.RLock()
+	defer si.dataMu.RUnlock()
+	return si.data.bytes()
 }
 
-func (si *stdinViewPane) execCommand(ctx context.Context, text string, inputBytes []byte) {
-	_data := new(bytes.Buffer)
-	tt := newTextLineTransformer()
-	w := transform.NewWriter(tview.ANSIWriter(si), tt)
-	mw := io.MultiWriter(w, _data)
+// reader returns a reader of the data of the pane. It stays readable after
+// the data is replaced, so the lock is only held while it's opened.
+func (si *stdinViewPane) reader() (io.Reader, error) {
+	si.dataMu.RLock()
+	defer si.dataMu.RUnlock()
+	return si.data.reader()
+}
 
+func (si *stdinViewPane) setData(input *store) {
+	si.setStore(input)
 	si.syncUpdate(func() {
-		si.data = []byte("")
+		si.exitErr = nil
 	})
-	cmd := sandboxedCommandContext(ctx, shell, text)
+	if si.shown == 0 {
+		si.display(input)
+	}
+}
 
-	cmd.Stdin = bytes.NewReader(inputBytes)
-	cmd.Stdout = mw
-	cmd.Run()
+// display shows the head of data in the pane.
+func (si *stdinViewPane) display(data *store) {
+	w := newPaneWriter(context.Background(), si.viewPane, io.Discard)
+	io.Copy(w, bytes.NewReader(data.bytes()))
+	w.Close()
+}
+
+func (si *stdinViewPane) execCommand(ctx context.Context, text string, input *store) {
+	data := newStore(true)
+	var w io.Writer = data
+	var pw *paneWriter
+	if si.shown == 0 {
+		pw = newPaneWriter(ctx, si.viewPane, data)
+		w = pw
+	}
+
+	si.setStore(storeBytes(nil))
+	stdin, err := input.reader()
+	if err != nil {
+		si.syncUpdate(func() {
+			si.exitErr = err
+		})
+		return
+	}
+	defer closeReader(stdin)
+	result := shellExecutor().Run(ctx, text, stdin, w, nil)
+	if pw != nil {
+		pw.Close()
+	}
 
 	select {
 	case <-ctx.Done():
+		data.close()
 	default:
+		si.setStore(data)
 		si.syncUpdate(func() {
-			si.data = _data.Bytes()
+			si.exitErr = resultError(result)
 		})
 	}
 }
 
 type stdoutViewPane struct {
 	*viewPane
+	input     []byte
+	data      []byte
+	prevData  []byte
+	diffMode  diffMode
+	diffWords bool
+	diffPrev  bool
 }
 
 func newStdoutViewPane() *stdoutViewPane {
@@ -385,17 +884,81 @@ func newStdoutViewPane() *stdoutViewPane {
 	return so
 }
 
-func (so *stdoutViewPane) execCommand(ctx context.Context, text string, inputBytes []byte) {
-	tt := newTextLineTransformer()
-	w := transform.NewWriter(tview.ANSIWriter(so), tt)
+// dataReader is the input of a stdout command: a store, or the stdin pane
+// whose store may be replaced while the command runs.
+type dataReader interface {
+	reader() (io.Reader, error)
+	bytes() []byte
+}
+
+// execCommand runs the command on input. Only the head of the output is
+// kept, as it is only displayed.
+func (so *stdoutViewPane) execCommand(ctx context.Context, text string, input dataReader) {
+	data := newStore(false)
+	var w io.Writer = data
+	if !so.diffing() {
+		pw := newPaneWriter(ctx, so.viewPane, data)
+		defer pw.Close()
+		w = pw
+	}
+
+	head := input.bytes()
+	stdin, err := input.reader()
+	if err != nil {
+		so.syncUpdate(func() {
+			so.exitErr = err
+		})
+		return
+	}
+	defer closeReader(stdin)
+	result := shellExecutor().Run(ctx, text, stdin, w, w)
+
+	select {
+	case <-ctx.Done():
+	default:
+		so.syncUpdate(func() {
+			so.input = head
+			so.prevData = so.data
+			so.data = data.bytes()
+			so.exitErr = resultError(result)
+		})
+	}
+}
+
+// newTextWriter returns a writer showing text in v through the line limit,
+// the whitespace visualization and the decoding of the input encoding. The
+// output of commands is decoded as well, as they usually keep the encoding
+// of their input.
+func newTextWriter(v *viewPane, whitespace, decoding bool) io.WriteCloser {
+	var w io.WriteCloser = transform.NewWriter(tview.ANSIWriter(v), newTextLineTransformer())
+	if whitespace {
+		w = newTransformWriter(w, &visibleTransformer{})
+	}
+	if decoding {
+		w = newTransformWriter(w, inputEncoding.NewDecoder())
+	}
+	return w
+}
 
-	cmd := sandboxedCommandContext(ctx, shell, text)
+// transformWriter is a transform.Writer which also closes the writer it
+// writes to.
+type transformWriter struct {
+	*transform.Writer
+	w io.WriteCloser
+}
 
-	cmd.Stdin = bytes.NewReader(inputBytes)
-	cmd.Stdout = w
-	cmd.Stderr = w
+func newTransformWriter(w io.WriteCloser, t transform.Transformer) *transformWriter {
+	return &transformWriter{
+		Writer: transform.NewWriter(w, t),
+		w:      w,
+	}
+}
 
-	cmd.Run()
+func (tw *transformWriter) Close() error {
+	if err := tw.Writer.Close(); err != nil {
+		return err
+	}
+	return tw.w.Close()
 }
 
 type textLineTransformer struct {
@@ -408,7 +971,7 @@ type textLineTransformer struct {
 func newTextLineTransformer() *textLineTransformer {
 	tt := &textLineTransformer{
 		line:  0,
-		limit: getTerminalHeight() - 3,
+		limit: max(getTerminalHeight()-3, scrollback),
 		temp:  []byte(""),
 	}
 	return tt
@@ -458,16 +1021,50 @@ func (tt *textLineTransformer) Transform(dst, src []byte, atEOF bool) (nDst, nSr
 }
 
 func main() {
-	runInSandbox() // Must be first: on Linux, may execve and never return.
+	engine.RunInSandbox() // Must be first: on Linux, may execve and never return.
+
+	conf, err := loadConfig(configPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+	splitRatio = conf.SplitRatio
+	scrollback = conf.Scrollback
+	refresh, err := time.ParseDuration(conf.Refresh)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: refresh: %v\n", err)
+		os.Exit(1)
+	}
 
 	flag.BoolVarP(&helpFlag, "help", "h", false, "Show help")
 	flag.BoolVarP(&versionFlag, "version", "v", false, "Show version")
 	flag.BoolVarP(&commandFlag, "command", "c", false, "Return commandline text")
+	flag.BoolVar(&batchFlag, "batch", false, "Run the command stage by stage without the UI, and print a report of each stage")
+	flag.IntVar(&batchLines, "batch-lines", 10, "Number of lines shown of the input and output of each stage with --batch")
+	flag.StringVar(&outputFormatFlag, "output-format", "text", "Select how the result is printed on exit (text, json)")
+	flag.BoolVar(&includeOutputFlag, "include-output", false, "Include the output of the pipeline in the result with --output-format json")
+	flag.IntVar(&cursorFlag, "cursor", cursorFlag, "Start with the cursor at this position in runes of the command, and print the final position before the text with -c")
+	flag.BoolVar(&viFlag, "vi", conf.Vi, "Use vi editing mode")
+	flag.StringVarP(&layoutFlag, "layout", "l", conf.Layout, "Select a pane layout (horizontal, vertical, single)")
+	flag.StringVar(&themeFlag, "theme", conf.Theme, "Select a color theme (dark, light, monochrome or a theme in the config)")
 	flag.StringVarP(&shell, "shell", "s", os.Getenv("SHELL"), "Select a shell to use")
+	flag.StringVar(&inputEncodingFlag, "input-encoding", "", "Select the encoding of stdin (e.g. Shift_JIS, EUC-JP, ISO-8859-1; detected by default)")
+	flag.BoolVar(&transcodeFlag, "transcode", false, "Convert stdin to UTF-8 before passing it to commands")
+	flag.StringArrayVarP(&inputFiles, "file", "f", nil, "Read the input from a file instead of stdin (can be repeated)")
+	flag.StringArrayVarP(&namedInputFlags, "input", "i", nil, "Add an input read from a file, or a command with '!', as $TP_IN1, $TP_IN2, ... ([NAME=]FILE or [NAME=]!COMMAND, can be repeated)")
+	flag.StringVar(&inputCommand, "input-cmd", "", "Read the input from the output of a command instead of stdin")
+	flag.BoolVar(&streamFlag, "stream", conf.Stream, "Read stdin in the background and keep only its last lines")
+	flag.IntVar(&streamBuffer, "stream-buffer", conf.StreamBuffer, "Size of stdin kept with --stream in MiB")
+	flag.DurationVar(&refreshInterval, "refresh", refresh, "Interval to run the pipeline on new input with --stream (0 to refresh with Ctrl-L only)")
+	flag.IntVar(&spillThresholdMiB, "spill-threshold", conf.SpillThreshold, "Size of a stage's output kept in memory in MiB, beyond which it is spilled to a temp file")
 	flag.Parse()
+	spillThreshold = spillThresholdMiB << 20
 
 	if helpFlag {
 		fmt.Fprintln(os.Stderr, "Usage of tp:")
+		fmt.Fprintln(os.Stderr, "  tp [flags] [command]")
+		fmt.Fprintln(os.Stderr, "  tp init bash|zsh|fish    Print the shell integration")
+		fmt.Fprintln(os.Stderr, "  tp serve SOCKET          Serve the preview with JSON-RPC on a unix socket")
 		flag.PrintDefaults()
 		os.Exit(0)
 	}
@@ -485,12 +1082,38 @@ func main() {
 		os.Exit(0)
 	}
 
+	if flag.NArg() == 2 && flag.Arg(0) == "init" {
+		s, err := shellInit(flag.Arg(1))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Print(s)
+		os.Exit(0)
+	}
+
 	if os.Getenv("SHELL") == "" {
 		fmt.Fprint(os.Stderr, "$SHELL not found, please select a shell by '-s' option")
 		os.Exit(1)
 	}
 
-	_, err := exec.LookPath(shell)
+	l, err := parseLayout(layoutFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if noColor() && !flag.CommandLine.Changed("theme") {
+		themeFlag = "monochrome"
+	}
+	currentTheme, err = loadTheme(themeFlag, conf.Themes)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	currentTheme.apply()
+
+	_, err = exec.LookPath(shell)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s not found", shell)
 		os.Exit(1)
@@ -501,12 +1124,86 @@ func main() {
 		os.Exit(1)
 	}
 
+	if flag.NArg() == 2 && flag.Arg(0) == "serve" {
+		if err := serve(flag.Arg(1)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	initCommand = flag.Arg(0)
 
-	if !isatty.IsTerminal(os.Stdin.Fd()) {
-		stdinBytes, _ = io.ReadAll(os.Stdin)
+	if err := checkOutputFormat(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := checkInputFlags(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if batchFlag && initCommand == "" {
+		fmt.Fprintln(os.Stderr, "--batch requires a command")
+		os.Exit(1)
+	}
+	if batchFlag && streamFlag {
+		fmt.Fprintln(os.Stderr, "--stream can't be used with --batch")
+		os.Exit(1)
+	}
+	if streamFlag && transcodeFlag {
+		fmt.Fprintln(os.Stderr, "--transcode can't be used with --stream")
+		os.Exit(1)
+	}
+	if hasInputSource() {
+		stdinData, err = loadInput()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read input: %v\n", err)
+			os.Exit(1)
+		}
+	} else if !isatty.IsTerminal(os.Stdin.Fd()) {
+		if streamFlag {
+			inputStream = newStdinStream(streamBuffer << 20)
+			go inputStream.readFrom(os.Stdin)
+		} else {
+			stdinData = newStore(true)
+			if _, err := io.Copy(stdinData, os.Stdin); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to read stdin: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	}
+	if err := loadNamedInputs(namedInputFlags); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read input: %v\n", err)
+		closeNamedInputs()
+		os.Exit(1)
+	}
+	if err := setInputEncoding(inputEncodingFlag, transcodeFlag); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
-	t := newTui()
-	os.Exit(t.start())
+	if batchFlag {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		stages := runBatch(ctx, initCommand, stdinData)
+		stop()
+		code := batchStatus(stages)
+		if err := printBatch(os.Stdout, stages); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+		}
+		stdinData.close()
+		closeNamedInputs()
+		os.Exit(code)
+	}
+
+	t := newTui(l)
+	code := t.start()
+	if err := printResult(t.result); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		code = 1
+	}
+	t.stdinPane.setStore(storeBytes(nil))
+	stdinData.close()
+	closeNamedInputs()
+	os.Exit(code)
 }
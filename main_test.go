@@ -5,9 +5,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/gdamore/tcell/v2"
 	"golang.org/x/text/transform"
 )
 
@@ -85,9 +90,9 @@ func TestSetData(t *testing.T) {
 	}
 	for _, tc := range cases {
 		si := newStdinViewPane()
-		si.setData([]byte(tc.input))
-		if !bytes.Equal(si.data, []byte(tc.result)) {
-			r := strings.Replace(fmt.Sprintf(`result:   "%s"`, string(si.data)), "\n", "\\n", -1)
+		si.setData(storeBytes([]byte(tc.input)))
+		if !bytes.Equal(si.data.bytes(), []byte(tc.result)) {
+			r := strings.Replace(fmt.Sprintf(`result:   "%s"`, string(si.data.bytes())), "\n", "\\n", -1)
 			e := strings.Replace(fmt.Sprintf(`expected: "%s"`, tc.result), "\n", "\\n", -1)
 			t.Errorf("\n%s\n%s", r, e)
 		}
@@ -118,9 +123,9 @@ func TestExecCommandStdin(t *testing.T) {
 		si := newStdinViewPane()
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
-		si.execCommand(ctx, tc.cmd, []byte(tc.stdin))
-		if !bytes.Equal(si.data, []byte(tc.result)) {
-			r := strings.Replace(fmt.Sprintf(`result:   "%s"`, string(si.data)), "\n", "\\n", -1)
+		si.execCommand(ctx, tc.cmd, storeBytes([]byte(tc.stdin)))
+		if !bytes.Equal(si.data.bytes(), []byte(tc.result)) {
+			r := strings.Replace(fmt.Sprintf(`result:   "%s"`, string(si.data.bytes())), "\n", "\\n", -1)
 			e := strings.Replace(fmt.Sprintf(`expected: "%s"`, tc.result), "\n", "\\n", -1)
 			t.Errorf("\n%s\n%s", r, e)
 		}
@@ -142,21 +147,30 @@ func TestExecCommandStdout(t *testing.T) {
 		cmd    string
 		stdin  string
 		result string
+		err    string
 	}{
 		{cmd: "echo a", stdin: "", result: "a\n"},
 		{cmd: "echo a 1>&2", stdin: "", result: "a\n"},
 		{cmd: "grep a", stdin: "a\nb\na\na\n", result: "a\na\na"},
+		{cmd: "echo a; exit 3", stdin: "", result: "a\n", err: "exit status 3"},
 	}
 	for _, tc := range cases {
 		so := newStdoutViewPane()
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
-		so.execCommand(ctx, tc.cmd, []byte(tc.stdin))
+		so.execCommand(ctx, tc.cmd, storeBytes([]byte(tc.stdin)))
 		if so.GetText(true) != tc.result {
 			r := strings.Replace(fmt.Sprintf(`result:   "%s"`, so.GetText(true)), "\n", "\\n", -1)
 			e := strings.Replace(fmt.Sprintf(`expected: "%s"`, tc.result), "\n", "\\n", -1)
 			t.Errorf("\n%s\n%s", r, e)
 		}
+		var err string
+		if so.exitErr != nil {
+			err = so.exitErr.Error()
+		}
+		if err != tc.err {
+			t.Errorf("\nresult:   %q\nexpected: %q", err, tc.err)
+		}
 	}
 }
 
@@ -229,3 +243,120 @@ func TestTransform(t *testing.T) {
 		}
 	}
 }
+
+// startTui runs a tui on a simulation screen until the test ends.
+func startTui(t *testing.T) *tui {
+	t.Helper()
+	shell = "sh"
+	getTerminalHeight = func() int {
+		return 6
+	}
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(80, 24)
+
+	tp := newTui(layoutHorizontal)
+	tp.SetScreen(screen)
+	done := make(chan struct{})
+	go func() {
+		tp.Run()
+		close(done)
+	}()
+	t.Cleanup(func() {
+		tp.QueueUpdate(func() {
+			tp.stdinPane.reset()
+			tp.stdoutPane.reset()
+		})
+		tp.Stop()
+		<-done
+	})
+	return tp
+}
+
+// queueUpdate runs fn on the UI goroutine of tp and fails when it doesn't
+// return in time.
+func queueUpdate(t *testing.T, tp *tui, fn func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go tp.QueueUpdateDraw(func() {
+		fn()
+		close(done)
+	})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("UI goroutine blocked")
+	}
+}
+
+// waitFor waits until cond, which is run on the UI goroutine of tp, is true.
+func waitFor(t *testing.T, tp *tui, cond func() bool) {
+	t.Helper()
+	for range 150 {
+		var ok bool
+		queueUpdate(t, tp, func() {
+			ok = cond()
+		})
+		if ok {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("condition not met in time")
+}
+
+// exited reports whether the title of v shows the exit status of a command.
+func exited(v *viewPane) func() bool {
+	return func() bool {
+		return strings.Contains(v.GetTitle(), "exit status")
+	}
+}
+
+// startPreview runs a tui on input with a stdout command which keeps running
+// until release is called. release waits until the command has exited.
+func startPreview(t *testing.T, input string) (tp *tui, release func()) {
+	t.Helper()
+	dir := t.TempDir()
+	started, fifo := filepath.Join(dir, "started"), filepath.Join(dir, "fifo")
+	if err := syscall.Mkfifo(fifo, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		stdinData = storeBytes(nil)
+	})
+	stdinData = storeBytes([]byte(input))
+	tp = startTui(t)
+	tp.stdinPane.setData(stdinData)
+
+	queueUpdate(t, tp, func() {
+		tp.cliPane.SetText(fmt.Sprintf("cat; : > %s; read _ < %s; exit 3", started, fifo))
+	})
+	waitFor(t, tp, func() bool {
+		_, err := os.Stat(started)
+		return err == nil
+	})
+	return tp, func() {
+		t.Helper()
+		// A command which has been run again may leave the old one reading
+		// the fifo until it is killed, so a line is written to any reader
+		// until the command exits.
+		waitFor(t, tp, func() bool {
+			if f, err := os.OpenFile(fifo, os.O_WRONLY|syscall.O_NONBLOCK, 0); err == nil {
+				f.WriteString("\n")
+				f.Close()
+			}
+			return exited(tp.stdoutPane.viewPane)()
+		})
+	}
+}
+
+func TestUpdateStdoutViewTitle(t *testing.T) {
+	tp, release := startPreview(t, "a\n")
+	queueUpdate(t, tp, func() {
+		tp.stdinPane.SetTitle(tp.stdinPane.title())
+		tp.stdoutPane.SetTitle(tp.stdoutPane.title())
+	})
+	release()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+
+	"github.com/minefuto/tp/engine"
+)
+
+// executor runs the commands of tp in the sandbox of the platform.
+var executor = &engine.Executor{
+	Sandbox: engine.DefaultSandbox(),
+	NewBuffer: func() engine.Buffer {
+		return storeBuffer{newStore(true)}
+	},
+}
+
+// shellExecutor returns the executor with the shell selected.
+func shellExecutor() *engine.Executor {
+	e := *executor
+	e.Shell = shell
+	return &e
+}
+
+// storeBuffer passes the output of a stage to the next one in a store, which
+// spills to a temp file.
+type storeBuffer struct {
+	*store
+}
+
+func (b storeBuffer) Reader() (io.Reader, error) {
+	return b.reader()
+}
+
+func (b storeBuffer) Close() error {
+	b.close()
+	return nil
+}
+
+func checkSandbox() error {
+	return executor.Sandbox.Check()
+}
+
+func sandboxedCommand(shell, text string) *exec.Cmd {
+	return executor.Sandbox.Command(context.Background(), shell, text)
+}
+
+// resultError returns the error shown in the title of a pane for the result
+// of its command.
+func resultError(r engine.Result) error {
+	switch {
+	case r.Err != nil:
+		return r.Err
+	case r.ExitStatus == -1:
+		return errors.New("killed by a signal")
+	case r.ExitStatus != 0:
+		return fmt.Errorf("exit status %d", r.ExitStatus)
+	}
+	return nil
+}
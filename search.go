@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// search is a regex search within the text displayed in a viewPane, that is
+// within the line limit. It only changes how the pane is rendered, not the
+// pipeline.
+type search struct {
+	query   string
+	err     error
+	matches int
+	current int
+	content string
+	plain   string
+	// limit is the number of lines shown if the output has been cut to
+	// them, or 0.
+	limit int
+}
+
+func (s *search) status() string {
+	switch {
+	case s.err != nil:
+		return fmt.Sprintf(" /%s %s(invalid regex)", tview.Escape(s.query), currentTheme.errorTag())
+	case s.matches == 0 && s.limit > 0:
+		return fmt.Sprintf(" /%s (no match in the first %d lines)", tview.Escape(s.query), s.limit)
+	case s.matches == 0:
+		return fmt.Sprintf(" /%s (no match)", tview.Escape(s.query))
+	default:
+		return fmt.Sprintf(" /%s (%d/%d)", tview.Escape(s.query), s.current+1, s.matches)
+	}
+}
+
+// highlight returns text escaped for tview with every match of re wrapped
+// in a numbered region, and the number of matches.
+func highlight(text string, re *regexp.Regexp) (string, int) {
+	mark, unmark := "[::u]", "[::-]"
+	if t := tag(currentTheme.Selection); t != "" {
+		mark, unmark = "[:"+currentTheme.Selection+"]", "[:-]"
+	}
+
+	var b strings.Builder
+	n, last := 0, 0
+	for _, m := range re.FindAllStringIndex(text, -1) {
+		if m[0] == m[1] {
+			continue
+		}
+		b.WriteString(tview.Escape(text[last:m[0]]))
+		b.WriteString(`["` + strconv.Itoa(n) + `"]` + mark)
+		b.WriteString(tview.Escape(text[m[0]:m[1]]))
+		b.WriteString(unmark + `[""]`)
+		last = m[1]
+		n++
+	}
+	b.WriteString(tview.Escape(text[last:]))
+	return b.String(), n
+}
+
+// setSearch highlights the matches of query in the pane.
+// An empty query clears the search.
+func (v *viewPane) setSearch(query string) {
+	if query == "" {
+		v.clearSearch()
+		return
+	}
+	if v.search == nil {
+		v.search = &search{content: v.GetText(false), plain: v.GetText(true)}
+		if limit := max(getTerminalHeight()-3, scrollback); strings.Count(v.search.plain, "\n") >= limit {
+			v.search.limit = limit
+		}
+		v.SetRegions(true)
+	}
+	s := v.search
+	s.query = query
+	s.current = 0
+
+	re, err := regexp.Compile(query)
+	s.err = err
+	if err != nil {
+		return
+	}
+
+	text, n := highlight(s.plain, re)
+	s.matches = n
+	v.SetText(text)
+	v.showMatch()
+}
+
+func (v *viewPane) clearSearch() {
+	if v.search == nil {
+		return
+	}
+	content := v.search.content
+	v.search = nil
+	v.SetRegions(false)
+	v.SetText(content)
+}
+
+// nextMatch moves the current match by delta, wrapping around.
+func (v *viewPane) nextMatch(delta int) {
+	s := v.search
+	if s == nil || s.matches == 0 {
+		return
+	}
+	s.current = (s.current + delta + s.matches) % s.matches
+	v.showMatch()
+}
+
+func (v *viewPane) showMatch() {
+	if v.search.matches == 0 {
+		v.Highlight()
+		return
+	}
+	v.Highlight(strconv.Itoa(v.search.current)).ScrollToHighlight()
+}
+
+// openSearch shows the search bar for the focused view pane.
+func (t *tui) openSearch(v *viewPane) {
+	t.searchPane = v
+	t.searchBar.SetText("")
+	if v.search != nil {
+		t.searchBar.SetText(v.search.query)
+	}
+	t.root.AddItem(t.searchBar, 1, 0, false)
+	t.SetFocus(t.searchBar)
+}
+
+func (t *tui) closeSearch(key tcell.Key) {
+	v := t.searchPane
+	if key == tcell.KeyEscape {
+		v.clearSearch()
+	}
+	v.SetTitle(v.title())
+	t.root.RemoveItem(t.searchBar)
+	t.SetFocus(v)
+}
+
+func newSearchBar() *tview.InputField {
+	s := tview.NewInputField()
+	s.SetLabel("/").
+		SetFieldWidth(0)
+	return s
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	useTheme(t, "monochrome")
+	cases := []struct {
+		text    string
+		query   string
+		result  string
+		matches int
+	}{
+		{text: "foo bar foo", query: "foo", result: `["0"][::u]foo[::-][""] bar ["1"][::u]foo[::-][""]`, matches: 2},
+		{text: "a[red]b", query: "b", result: `a[red[]["0"][::u]b[::-][""]`, matches: 1},
+		{text: "abc", query: "x*", result: "abc", matches: 0},
+	}
+	for _, tc := range cases {
+		result, matches := highlight(tc.text, regexp.MustCompile(tc.query))
+		if result != tc.result || matches != tc.matches {
+			t.Errorf("\nresult:   %s %d\nexpected: %s %d", result, matches, tc.result, tc.matches)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	useTheme(t, "monochrome")
+	getTerminalHeight = func() int {
+		return 7
+	}
+	v := newViewPane("stdout/stderr")
+	v.SetText("foo\nbar\nfoo\n")
+
+	v.setSearch("fo+")
+	if v.title() != "stdout/stderr /fo+ (1/2)" {
+		t.Errorf("result: %s", v.title())
+	}
+	v.nextMatch(1)
+	if v.title() != "stdout/stderr /fo+ (2/2)" {
+		t.Errorf("result: %s", v.title())
+	}
+	v.nextMatch(1)
+	if v.title() != "stdout/stderr /fo+ (1/2)" {
+		t.Errorf("result: %s", v.title())
+	}
+	v.setSearch("(")
+	if v.title() != "stdout/stderr /( [::b](invalid regex)" {
+		t.Errorf("result: %s", v.title())
+	}
+	v.clearSearch()
+	if v.title() != "stdout/stderr" || v.GetText(false) != "foo\nbar\nfoo\n" {
+		t.Errorf("result: %s %q", v.title(), v.GetText(false))
+	}
+	v.setSearch("baz")
+	if v.title() != "stdout/stderr /baz (no match)" {
+		t.Errorf("result: %s", v.title())
+	}
+	v.clearSearch()
+
+	// Lines beyond the line limit aren't shown, so they aren't searched.
+	v.SetText("foo\nbar\nfoo\nbar\n")
+	v.setSearch("baz")
+	if v.title() != "stdout/stderr /baz (no match in the first 4 lines)" {
+		t.Errorf("result: %s", v.title())
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"unicode/utf8"
+)
+
+// serveOutputLimit is the size of the output of a stage sent to the client.
+// The rest is only counted, as in the preview.
+const serveOutputLimit = 1 << 20
+
+// JSON-RPC 2.0 error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+// serve listens on the unix socket at path and serves each connection until
+// interrupted.
+func serve(path string) error {
+	l, err := listen(path)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go newSession(conn).serve()
+	}
+}
+
+// listen listens on the unix socket at path, replacing a stale one. Only the
+// user can connect, as the commands run with the user's permissions.
+func listen(path string) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// session is a connection to tp serve. It has its own input and pipeline,
+// and runs one pipeline at a time.
+type session struct {
+	conn io.ReadWriteCloser
+	mu   sync.Mutex
+	enc  *json.Encoder
+
+	input  *store
+	run    int
+	cancel context.CancelFunc
+}
+
+func newSession(conn io.ReadWriteCloser) *session {
+	enc := json.NewEncoder(conn)
+	enc.SetEscapeHTML(false)
+	return &session{
+		conn:   conn,
+		enc:    enc,
+		input:  storeBytes(nil),
+		cancel: func() {},
+	}
+}
+
+// serve reads a request per line until the connection is closed.
+func (s *session) serve() {
+	defer s.conn.Close()
+	defer s.cancelRun()
+
+	r := bufio.NewReader(s.conn)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			s.handle(line)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *session) send(v any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enc.Encode(v)
+}
+
+func (s *session) notify(method string, params any) {
+	s.send(rpcNotification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *session) handle(line []byte) {
+	var req rpcRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		s.send(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: err.Error()}})
+		return
+	}
+	if req.JSONRPC != "2.0" {
+		id := req.ID
+		if id == nil {
+			id = json.RawMessage("null")
+		}
+		s.send(rpcResponse{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: rpcInvalidRequest, Message: `jsonrpc must be "2.0"`}})
+		return
+	}
+	result, rerr := s.call(req.Method, req.Params)
+	if req.ID == nil {
+		return
+	}
+	s.send(rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rerr})
+}
+
+func (s *session) call(method string, params json.RawMessage) (any, *rpcError) {
+	switch method {
+	case "setInput":
+		var p struct {
+			Data string `json:"data"`
+		}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		s.cancelRun()
+		s.input = storeBytes([]byte(p.Data))
+		return map[string]int{"bytes": len(p.Data)}, nil
+
+	case "setPipeline":
+		var p struct {
+			Text string `json:"text"`
+		}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.startRun(p.Text), nil
+
+	case "cancel":
+		s.cancelRun()
+		return map[string]int{"run": s.run}, nil
+
+	case "sandboxStatus":
+		status := map[string]any{"sandbox": executor.Sandbox.Name(), "available": true}
+		if err := checkSandbox(); err != nil {
+			status["available"], status["error"] = false, err.Error()
+		}
+		return status, nil
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: "method not found: " + method}
+}
+
+func decodeParams(params json.RawMessage, v any) *rpcError {
+	if params == nil {
+		return &rpcError{Code: rpcInvalidParams, Message: "params are required"}
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *session) cancelRun() {
+	s.cancel()
+}
+
+// startRun cancels the running pipeline and runs text on the input in the
+// background. The output of each stage is sent with stage.output
+// notifications, followed by stage.done, and pipeline.done at the end, which
+// has no exit status if the run was cancelled.
+func (s *session) startRun(text string) any {
+	s.cancel()
+	s.run++
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	run, input := s.run, s.input
+	go func() {
+		defer cancel()
+		stages := runStages(ctx, text, input, &runWatcher{s: s, run: run})
+		done := map[string]any{"run": run, "cancelled": ctx.Err() != nil}
+		if ctx.Err() == nil {
+			done["exit_status"] = batchStatus(stages)
+		}
+		s.notify("pipeline.done", done)
+	}()
+	return map[string]any{"run": run, "stages": splitStages(text)}
+}
+
+// runWatcher sends the progress of a run to the client.
+type runWatcher struct {
+	s   *session
+	run int
+}
+
+func (w *runWatcher) output(i int) io.Writer {
+	return &outputNotifier{s: w.s, run: w.run, stage: i}
+}
+
+// stageDone is the params of a stage.done notification.
+type stageDone struct {
+	Run        int    `json:"run"`
+	Stage      int    `json:"stage"`
+	Command    string `json:"command"`
+	ExitStatus int    `json:"exit_status"`
+	DurationMs int64  `json:"duration_ms"`
+	Lines      int64  `json:"lines"`
+	Bytes      int64  `json:"bytes"`
+	Stderr     string `json:"stderr,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+func (w *runWatcher) done(i int, stage batchStage) {
+	w.s.notify("stage.done", stageDone{
+		Run:        w.run,
+		Stage:      i,
+		Command:    stage.Command,
+		ExitStatus: stage.ExitStatus,
+		DurationMs: stage.DurationMs,
+		Lines:      stage.Output.Lines,
+		Bytes:      stage.Output.Bytes,
+		Stderr:     stage.Stderr.Head,
+		Error:      stage.Error,
+	})
+}
+
+// outputNotifier sends the output of a stage up to serveOutputLimit with
+// stage.output notifications. A rune split between writes is sent with the
+// next one.
+type outputNotifier struct {
+	s       *session
+	run     int
+	stage   int
+	sent    int
+	pending []byte
+}
+
+func (o *outputNotifier) Write(p []byte) (int, error) {
+	if o.sent >= serveOutputLimit {
+		return len(p), nil
+	}
+	b := append(o.pending, p...)
+	b = b[:min(len(b), serveOutputLimit-o.sent)]
+	n := len(b)
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		if utf8.RuneStart(b[len(b)-i]) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				n = len(b) - i
+			}
+			break
+		}
+	}
+	o.pending = append([]byte(nil), b[n:]...)
+	if n > 0 {
+		o.sent += n
+		o.s.notify("stage.output", map[string]any{"run": o.run, "stage": o.stage, "data": string(b[:n])})
+	}
+	return len(p), nil
+}
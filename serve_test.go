@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSession(t *testing.T) {
+	shell = "sh"
+	server, client := net.Pipe()
+	go newSession(server).serve()
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(10 * time.Second))
+
+	requests := []string{
+		`{"jsonrpc":"2.0","id":1,"method":"setInput","params":{"data":"b\na\nb\n"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"setPipeline","params":{"text":"sleep 10"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"cancel"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"setPipeline","params":{"text":"sort | uniq"}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"setPipeline"}`,
+		`{"jsonrpc":"2.0","id":6,"method":"foo"}`,
+		`{"id":7,"method":"cancel"}`,
+		`{"jsonrpc":"1.0","method":"cancel"}`,
+		`{`,
+	}
+	go func() {
+		for _, r := range requests {
+			client.Write([]byte(r + "\n"))
+		}
+	}()
+
+	var lines []string
+	done := 0
+	scanner := bufio.NewScanner(client)
+	for done < 2 && scanner.Scan() {
+		line := scanner.Text()
+		var m struct {
+			Method string `json:"method"`
+		}
+		json.Unmarshal([]byte(line), &m)
+		switch m.Method {
+		case "pipeline.done":
+			done++
+		case "stage.done":
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	expected := []string{
+		`{"jsonrpc":"2.0","id":1,"result":{"bytes":6}}`,
+		`{"jsonrpc":"2.0","id":2,"result":{"run":1,"stages":["sleep 10"]}}`,
+		`{"jsonrpc":"2.0","id":3,"result":{"run":1}}`,
+		`{"jsonrpc":"2.0","id":4,"result":{"run":2,"stages":["sort","uniq"]}}`,
+		`{"jsonrpc":"2.0","id":5,"error":{"code":-32602,"message":"params are required"}}`,
+		`{"jsonrpc":"2.0","id":6,"error":{"code":-32601,"message":"method not found: foo"}}`,
+		`{"jsonrpc":"2.0","id":7,"error":{"code":-32600,"message":"jsonrpc must be \"2.0\""}}`,
+		`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"jsonrpc must be \"2.0\""}}`,
+		`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"unexpected end of JSON input"}}`,
+	}
+	notifications := []string{
+		`{"jsonrpc":"2.0","method":"pipeline.done","params":{"cancelled":true,"run":1}}`,
+		`{"jsonrpc":"2.0","method":"stage.output","params":{"data":"a\nb\nb\n","run":2,"stage":0}}`,
+		`{"jsonrpc":"2.0","method":"stage.output","params":{"data":"a\nb\n","run":2,"stage":1}}`,
+		`{"jsonrpc":"2.0","method":"pipeline.done","params":{"cancelled":false,"exit_status":0,"run":2}}`,
+	}
+	for _, e := range append(expected, notifications...) {
+		found := false
+		for _, l := range lines {
+			found = found || l == e
+		}
+		if !found {
+			t.Errorf("\nresult:   %s\nexpected: %s", strings.Join(lines, "\n          "), e)
+		}
+	}
+}
+
+func TestOutputNotifier(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	o := &outputNotifier{s: newSession(server), run: 1}
+
+	go func() {
+		o.Write([]byte("a\xe3\x81"))
+		o.Write([]byte("\x82b"))
+		server.Close()
+	}()
+	var data []string
+	scanner := bufio.NewScanner(client)
+	for scanner.Scan() {
+		var m struct {
+			Params struct {
+				Data string `json:"data"`
+			} `json:"params"`
+		}
+		json.Unmarshal(scanner.Bytes(), &m)
+		data = append(data, m.Params.Data)
+	}
+	if strings.Join(data, ",") != "a,あb" {
+		t.Errorf("result: %q", data)
+	}
+}
+
+func TestListen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tp.sock")
+	l, err := listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0o600 {
+		t.Errorf("\nresult:   %o\nexpected: %o", perm, 0o600)
+	}
+}
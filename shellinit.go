@@ -0,0 +1,49 @@
+package main
+
+import "fmt"
+
+// shellInits are the integrations printed by `tp init`. Each binds a key
+// which inserts a pipe at the cursor and opens tp -c on the line buffer with
+// --cursor, then puts the result back in the line editor with the cursor
+// where it was left in tp. The buffer is left as it is if tp fails.
+var shellInits = map[string]string{
+	"bash": `transparent-pipe() {
+  local result
+  result=$(tp -c --cursor $((READLINE_POINT + 1)) "${READLINE_LINE:0:READLINE_POINT}|${READLINE_LINE:READLINE_POINT}") || return
+  READLINE_LINE=${result#*$'\n'}
+  READLINE_POINT=${result%%$'\n'*}
+}
+bind -x '"\C-x|": transparent-pipe'
+`,
+	"zsh": `transparent-pipe() {
+  local result
+  result=$(tp -c --cursor $((CURSOR + 1)) "${LBUFFER}|${RBUFFER}") || return
+  BUFFER=${result#*$'\n'}
+  CURSOR=${result%%$'\n'*}
+  zle reset-prompt
+}
+zle -N transparent-pipe
+bindkey '^|' transparent-pipe
+`,
+	"fish": `function transparent-pipe
+    set -l cursor (commandline -C)
+    set -l buffer (commandline -b | string collect)
+    set -l left (string sub -l $cursor -- $buffer | string collect)
+    set -l right (string sub -s (math $cursor + 1) -- $buffer | string collect)
+    set -l result (tp -c --cursor (math $cursor + 1) "$left|$right"); or return
+    commandline -r -- (string join \n -- $result[2..-1] | string collect)
+    commandline -C $result[1]
+    commandline -f repaint
+end
+bind \cx\| transparent-pipe
+`,
+}
+
+// shellInit returns the integration for the shell given to `tp init`.
+func shellInit(shell string) (string, error) {
+	s, ok := shellInits[shell]
+	if !ok {
+		return "", fmt.Errorf("unsupported shell: %s (bash, zsh or fish)", shell)
+	}
+	return s, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestShellInit(t *testing.T) {
+	cases := []struct {
+		shell   string
+		syntax  []string
+		isError bool
+	}{
+		{shell: "bash", syntax: []string{"bash", "-n"}},
+		{shell: "zsh", syntax: []string{"zsh", "-n"}},
+		{shell: "fish", syntax: []string{"fish", "--no-execute"}},
+		{shell: "tcsh", isError: true},
+	}
+	for _, tc := range cases {
+		result, err := shellInit(tc.shell)
+		if (err != nil) != tc.isError {
+			t.Errorf("shell: %s, error: %v", tc.shell, err)
+		}
+		if err != nil {
+			continue
+		}
+		if !strings.Contains(result, "tp -c") {
+			t.Errorf("result: %s", result)
+		}
+		if _, err := exec.LookPath(tc.syntax[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(tc.syntax[0], tc.syntax[1:]...)
+		cmd.Stdin = strings.NewReader(result)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("%s: %v: %s", tc.shell, err, out)
+		}
+	}
+}
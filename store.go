@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// spillThreshold is the number of bytes of a stage's data kept in memory.
+var spillThreshold = 64 << 20
+
+// stdinData is the data read from stdin.
+var stdinData = storeBytes(nil)
+
+// store keeps the data of a stage. Up to spillThreshold bytes are kept in
+// memory. Beyond that, a spilling store moves the data to a temp file which
+// is passed to commands as their stdin, and keeps only the head in memory
+// for display. Other stores discard the rest of the data.
+type store struct {
+	head  []byte
+	file  *os.File
+	size  int64
+	spill bool
+	keep  bool // the file is an input file, not a temp file
+	err   error
+}
+
+func newStore(spill bool) *store {
+	return &store{spill: spill}
+}
+
+// storeBytes returns a store holding all of b in memory.
+func storeBytes(b []byte) *store {
+	return &store{head: b, size: int64(len(b))}
+}
+
+func (s *store) Write(p []byte) (int, error) {
+	s.size += int64(len(p))
+	if s.file == nil && len(s.head)+len(p) > spillThreshold && s.spill {
+		s.file, s.err = os.CreateTemp("", name+"-*")
+		if s.err == nil {
+			_, s.err = s.file.Write(s.head)
+		}
+	}
+	if s.file != nil && s.err == nil {
+		_, s.err = s.file.Write(p)
+	}
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	if n := spillThreshold - len(s.head); n > 0 {
+		s.head = append(s.head, p[:min(n, len(p))]...)
+	}
+	return len(p), nil
+}
+
+// openStore returns a store of the file at path. The file is passed to
+// commands as it is instead of being copied.
+func openStore(path string) (*store, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	head, err := io.ReadAll(io.LimitReader(f, int64(spillThreshold)))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &store{head: head, file: f, size: info.Size(), keep: true}, nil
+}
+
+// bytes returns the data kept in memory, which is all of it unless the
+// store has spilled or discarded data.
+func (s *store) bytes() []byte {
+	return s.head
+}
+
+func (s *store) len() int64 {
+	return s.size
+}
+
+// reader returns a reader of all the data. For a spilled store, it is
+// a new file descriptor of the temp file, which the caller must close.
+func (s *store) reader() (io.Reader, error) {
+	if s.file == nil {
+		return bytes.NewReader(s.head), nil
+	}
+	return os.Open(s.file.Name())
+}
+
+// close removes the temp file of the store. It may be called more than once.
+func (s *store) close() {
+	if s.file == nil {
+		return
+	}
+	s.file.Close()
+	if !s.keep {
+		os.Remove(s.file.Name())
+	}
+}
+
+// closeReader closes r if it is a file from store.reader.
+func closeReader(r io.Reader) {
+	if c, ok := r.(io.Closer); ok {
+		c.Close()
+	}
+}
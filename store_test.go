@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	defer func(n int) {
+		spillThreshold = n
+	}(spillThreshold)
+	spillThreshold = 4
+
+	cases := []struct {
+		spill   bool
+		writes  []string
+		head    string
+		data    string
+		spilled bool
+		size    int64
+	}{
+		{spill: true, writes: []string{"ab", "cd"}, head: "abcd", data: "abcd", spilled: false, size: 4},
+		{spill: true, writes: []string{"abc", "def", "g"}, head: "abcd", data: "abcdefg", spilled: true, size: 7},
+		{spill: false, writes: []string{"abc", "def", "g"}, head: "abcd", data: "abcd", spilled: false, size: 7},
+	}
+	for _, tc := range cases {
+		s := newStore(tc.spill)
+		for _, w := range tc.writes {
+			s.Write([]byte(w))
+		}
+		r, err := s.reader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, spilled := r.(*os.File)
+		data, _ := io.ReadAll(r)
+		closeReader(r)
+
+		if string(s.bytes()) != tc.head || string(data) != tc.data || spilled != tc.spilled || s.len() != tc.size {
+			t.Errorf("\nresult:   %q %q %v %d\nexpected: %q %q %v %d", s.bytes(), data, spilled, s.len(), tc.head, tc.data, tc.spilled, tc.size)
+		}
+
+		s.close()
+		s.close()
+		if s.file != nil {
+			if _, err := os.Stat(s.file.Name()); !os.IsNotExist(err) {
+				t.Errorf("temp file is not removed: %v", err)
+			}
+		}
+	}
+}
+
+func TestSpilledStage(t *testing.T) {
+	shell = "sh"
+	getTerminalHeight = func() int {
+		return 6
+	}
+	defer func(n int) {
+		spillThreshold = n
+	}(spillThreshold)
+	spillThreshold = 4
+
+	input := newStore(true)
+	input.Write([]byte("aa\nb\naa\nb\n"))
+	defer input.close()
+
+	si := newStdinViewPane()
+	si.execCommand(context.Background(), "grep a", input)
+	so := newStdoutViewPane()
+	so.execCommand(context.Background(), "wc -l | tr -d ' '", si.data)
+	if si.data.file == nil || string(si.data.bytes()) != "aa\na" || so.GetText(true) != "2\n" {
+		t.Errorf("result: %q %q", si.data.bytes(), so.GetText(true))
+	}
+	si.setStore(storeBytes(nil))
+}
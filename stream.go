@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const streamReadSize = 32 * 1024
+
+// inputStream is the stream of stdin with --stream, nil otherwise.
+var inputStream *stdinStream
+
+// stdinStream reads stdin in the background and keeps its last lines up to
+// limit bytes, so the UI starts at once and an infinite input such as
+// `tail -f` fits in memory.
+type stdinStream struct {
+	mu        sync.Mutex
+	data      []byte
+	start     int
+	limit     int
+	truncated bool
+	dropped   int // the number of lines dropped
+	eof       bool
+	version   int
+	handoff   *io.PipeWriter
+}
+
+func newStdinStream(limit int) *stdinStream {
+	return &stdinStream{limit: limit}
+}
+
+func (s *stdinStream) readFrom(r io.Reader) {
+	buf := make([]byte, streamReadSize)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			s.write(buf[:n])
+		}
+		if err != nil {
+			s.close()
+			return
+		}
+	}
+}
+
+func (s *stdinStream) write(b []byte) {
+	s.mu.Lock()
+	if handoff := s.handoff; handoff != nil {
+		// The pipe blocks until the command reads, so the lock is released.
+		s.mu.Unlock()
+		handoff.Write(b)
+		return
+	}
+	defer s.mu.Unlock()
+
+	s.data = append(s.data, b...)
+	s.version++
+	if len(s.data)-s.start <= s.limit {
+		return
+	}
+
+	// Drop the oldest lines, and compact once half of the data is dropped.
+	s.truncated = true
+	start := s.start
+	s.start = len(s.data) - s.limit
+	if i := bytes.IndexByte(s.data[s.start:], '\n'); i != -1 {
+		s.start += i + 1
+	}
+	s.dropped += bytes.Count(s.data[start:s.start], []byte("\n"))
+	if s.start > len(s.data)/2 {
+		s.data = s.data[:copy(s.data, s.data[s.start:])]
+		s.start = 0
+	}
+}
+
+func (s *stdinStream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.eof = true
+	s.version++
+	if s.handoff != nil {
+		s.handoff.Close()
+	}
+}
+
+// snapshot returns a copy of the kept input, the number of lines dropped
+// before it, its version which changes whenever input arrives, and its
+// status for the title of the stdin pane.
+func (s *stdinStream) snapshot() ([]byte, int, int, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var status []string
+	if !s.eof {
+		status = append(status, "live")
+	}
+	if s.truncated {
+		status = append(status, "truncated")
+	}
+	var st string
+	if len(status) > 0 {
+		st = " (" + strings.Join(status, ", ") + ")"
+	}
+	return bytes.Clone(s.data[s.start:]), s.dropped, s.version, st
+}
+
+func (s *stdinStream) currentVersion() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version
+}
+
+// reader returns the kept input followed by the rest of stdin. The stream
+// stops keeping input once it is called.
+func (s *stdinStream) reader() io.Reader {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := bytes.NewReader(s.data[s.start:])
+	if s.eof {
+		return data
+	}
+	pr, pw := io.Pipe()
+	s.handoff = pw
+	return io.MultiReader(data, pr)
+}
+
+// refresh runs the pipeline again on the input read so far, or reloads the
+// input files or command. The stdout preview runs when the stdin pane has
+// been updated.
+func (t *tui) refresh() {
+	if t.refreshing {
+		return
+	}
+	if hasInputSource() {
+		t.reloadInput()
+		return
+	}
+	if inputStream == nil {
+		return
+	}
+	data, dropped, version, status := inputStream.snapshot()
+	stdinData = storeBytes(data)
+	t.streamDropped, t.streamVersion = dropped, version
+	if t.stdinPane.shown == 0 {
+		t.stdinPane.syncUpdate(func() {
+			t.stdinPane.name = "stdin" + status
+		})
+	}
+
+	t.refreshing = true
+	t.stdinPane.reset()
+	t.updateStdinView()
+}
+
+// watchStream refreshes the pipeline every interval while input arrives.
+func (t *tui) watchStream(interval time.Duration) {
+	for range time.Tick(interval) {
+		version := inputStream.currentVersion()
+		t.QueueUpdate(func() {
+			if version != t.streamVersion {
+				t.refresh()
+			}
+		})
+	}
+}
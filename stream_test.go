@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestStdinStream(t *testing.T) {
+	cases := []struct {
+		writes  []string
+		eof     bool
+		result  string
+		dropped int
+		status  string
+	}{
+		{writes: []string{"a\n", "b\n"}, eof: false, result: "a\nb\n", dropped: 0, status: " (live)"},
+		{writes: []string{"a\n", "b\n"}, eof: true, result: "a\nb\n", dropped: 0, status: ""},
+		{writes: []string{"aaa\nbbb\n", "ccc\n"}, eof: false, result: "ccc\n", dropped: 2, status: " (live, truncated)"},
+		{writes: []string{"aa\nbb\n", "cc\ndd"}, eof: true, result: "cc\ndd", dropped: 2, status: " (truncated)"},
+		{writes: []string{"aaaaaaaaaa"}, eof: true, result: "aaaaaa", dropped: 0, status: " (truncated)"},
+		{writes: []string{"a\n", "b\n", "c\n", "d\n", "e\n"}, eof: true, result: "c\nd\ne\n", dropped: 2, status: " (truncated)"},
+	}
+	for _, tc := range cases {
+		s := newStdinStream(6)
+		for _, w := range tc.writes {
+			s.write([]byte(w))
+		}
+		if tc.eof {
+			s.close()
+		}
+		data, dropped, _, status := s.snapshot()
+		if string(data) != tc.result || dropped != tc.dropped || status != tc.status {
+			t.Errorf("\nresult:   %q %d %q\nexpected: %q %d %q", data, dropped, status, tc.result, tc.dropped, tc.status)
+		}
+	}
+}
+
+func TestStdinStreamReader(t *testing.T) {
+	r, w := io.Pipe()
+	s := newStdinStream(1 << 20)
+	done := make(chan struct{})
+	go func() {
+		s.readFrom(r)
+		close(done)
+	}()
+
+	w.Write([]byte("a\n"))
+	for s.currentVersion() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	reader := s.reader()
+	go func() {
+		w.Write([]byte("b\n"))
+		w.Close()
+	}()
+
+	b, _ := io.ReadAll(reader)
+	<-done
+	if string(b) != "a\nb\n" {
+		t.Errorf("result: %q", b)
+	}
+	if data, _, _, _ := s.snapshot(); string(data) != "a\n" {
+		t.Errorf("result: %q", data)
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	kindJSON    = "json"
+	kindCSV     = "csv"
+	kindTSV     = "tsv"
+	kindColumns = "columns"
+
+	// jsonExpandLevel is the depth up to which JSON nodes are expanded.
+	jsonExpandLevel = 2
+)
+
+// jsonValue is a decoded JSON value which keeps the order of object keys.
+type jsonValue struct {
+	key      string
+	value    string // scalar value or "{" / "[" for objects and arrays
+	children []*jsonValue
+}
+
+// parseJSON decodes a JSON document or JSON lines.
+func parseJSON(data []byte) ([]*jsonValue, error) {
+	if c := data[0]; c != '{' && c != '[' {
+		return nil, errors.New("not a JSON object or array")
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var values []*jsonValue
+	for {
+		v, err := decodeJSON(dec, "")
+		if err == io.EOF {
+			return values, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+}
+
+func decodeJSON(dec *json.Decoder, key string) (*jsonValue, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	v := &jsonValue{key: key}
+
+	switch t := t.(type) {
+	case json.Delim:
+		v.value = t.String()
+		for i := 0; dec.More(); i++ {
+			childKey := strconv.Itoa(i)
+			if t == '{' {
+				k, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				childKey = k.(string)
+			}
+			child, err := decodeJSON(dec, childKey)
+			if err != nil {
+				return nil, err
+			}
+			v.children = append(v.children, child)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	case string:
+		v.value = strconv.Quote(t)
+	case nil:
+		v.value = "null"
+	default:
+		v.value = fmt.Sprint(t)
+	}
+	return v, nil
+}
+
+func (v *jsonValue) label() string {
+	var value string
+	switch v.value {
+	case "{":
+		value = fmt.Sprintf("{…} (%d keys)", len(v.children))
+	case "[":
+		value = fmt.Sprintf("[…] (%d items)", len(v.children))
+	default:
+		value = tview.Escape(v.value)
+	}
+	if v.key == "" {
+		return value
+	}
+	key := tview.Escape(v.key)
+	if t := tag(currentTheme.Prompt); t != "" {
+		key = t + key + "[-]"
+	}
+	return key + ": " + value
+}
+
+func (v *jsonValue) node(level int) *tview.TreeNode {
+	n := tview.NewTreeNode(v.label()).
+		SetExpanded(level < jsonExpandLevel)
+	for _, c := range v.children {
+		n.AddChild(c.node(level + 1))
+	}
+	return n
+}
+
+func newJSONView(values []*jsonValue) *tview.TreeView {
+	var root *tview.TreeNode
+	if len(values) == 1 {
+		root = values[0].node(0)
+	} else {
+		root = tview.NewTreeNode(fmt.Sprintf("JSON lines (%d)", len(values)))
+		for i, v := range values {
+			v.key = strconv.Itoa(i + 1)
+			root.AddChild(v.node(1))
+		}
+	}
+
+	tree := tview.NewTreeView().
+		SetRoot(root).
+		SetCurrentNode(root)
+	tree.SetSelectedFunc(func(node *tview.TreeNode) {
+		node.SetExpanded(!node.IsExpanded())
+	})
+	return tree
+}
+
+func parseDelimited(data []byte, comma rune) ([][]string, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = comma
+	r.LazyQuotes = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) < 2 || len(rows[0]) < 2 {
+		return nil, errors.New("not a table")
+	}
+	return rows, nil
+}
+
+// parseColumns splits whitespace-aligned output such as ps or df into rows.
+// Fields beyond the number of header fields are joined into the last column.
+func parseColumns(data []byte) ([][]string, error) {
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	header := strings.Fields(lines[0])
+	if len(lines) < 2 || len(header) < 2 {
+		return nil, errors.New("not a table")
+	}
+
+	rows := make([][]string, 0, len(lines))
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < len(header) {
+			return nil, errors.New("not a table")
+		}
+		last := len(header) - 1
+		rows = append(rows, append(fields[:last:last], strings.Join(fields[last:], " ")))
+	}
+	return rows, nil
+}
+
+// newTableView shows rows with a row of column numbers and the first row
+// as column headers.
+func newTableView(rows [][]string) *tview.Table {
+	table := tview.NewTable().
+		SetFixed(2, 0).
+		SetSelectable(true, false).
+		SetSeparator(tview.Borders.Vertical)
+
+	for i := range rows[0] {
+		table.SetCell(0, i, tview.NewTableCell(strconv.Itoa(i+1)).
+			SetTextColor(color(currentTheme.Prompt)).
+			SetSelectable(false))
+	}
+	for r, row := range rows {
+		for c, field := range row {
+			cell := tview.NewTableCell(tview.Escape(field))
+			if r == 0 {
+				cell.SetAttributes(tcell.AttrBold).SetSelectable(false)
+			}
+			table.SetCell(r+1, c, cell)
+		}
+	}
+	return table
+}
+
+// parseTable detects CSV, TSV or whitespace-aligned columns in data and
+// returns its rows and kind, or nil if data is not a table.
+func parseTable(data []byte) ([][]string, string) {
+	firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+	switch {
+	case bytes.ContainsRune(firstLine, '\t'):
+		if rows, err := parseDelimited(data, '\t'); err == nil {
+			return rows, kindTSV
+		}
+	case bytes.ContainsRune(firstLine, ','):
+		if rows, err := parseDelimited(data, ','); err == nil {
+			return rows, kindCSV
+		}
+	}
+	if rows, err := parseColumns(data); err == nil {
+		return rows, kindColumns
+	}
+	return nil, ""
+}
+
+// newStructuredView detects the type of data and returns it with a view
+// of the data, or a nil view if data has no structure. Tables are limited
+// to the given number of lines.
+func newStructuredView(data []byte, lines int) (string, tview.Primitive) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return "", nil
+	}
+	if values, err := parseJSON(data); err == nil {
+		return kindJSON, newJSONView(values)
+	}
+	rows, kind := parseTable(headLines(data, lines))
+	if rows == nil {
+		return "", nil
+	}
+	return kind, newTableView(rows)
+}
+
+// setContent keeps data as the content of the pane and rebuilds the
+// structured view if it is enabled.
+func (v *viewPane) setContent(data []byte) {
+	v.content = data
+	v.updateStructured()
+}
+
+func (v *viewPane) toggleStructured() {
+	v.structuredOn = !v.structuredOn
+	v.updateStructured()
+}
+
+func (v *viewPane) updateStructured() {
+	v.structured = nil
+	v.kind = ""
+	if v.structuredOn {
+		v.kind, v.structured = newStructuredView(v.content, max(getTerminalHeight()-3, scrollback))
+	}
+}
+
+// structuredStatus returns the detected type for the title of the pane.
+func (v *viewPane) structuredStatus() string {
+	switch {
+	case v.structured != nil:
+		return " (" + v.kind + ")"
+	case v.structuredOn && len(bytes.TrimSpace(v.content)) > 0:
+		return " (no structure)"
+	}
+	return ""
+}
+
+// Draw draws the structured view inside the pane's border if it is enabled,
+// otherwise the text.
+func (v *viewPane) Draw(screen tcell.Screen) {
+	if v.structured == nil {
+		v.drawText(screen)
+		return
+	}
+	v.SetBorderPadding(0, 0, 0, 0)
+	v.DrawForSubclass(screen, v)
+	x, y, width, height := v.GetInnerRect()
+	v.structured.SetRect(x, y, width, height)
+	v.structured.Draw(screen)
+}
+
+func (v *viewPane) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	if v.structured == nil {
+		return v.TextView.InputHandler()
+	}
+	return v.WrapInputHandler(v.structured.InputHandler())
+}
+
+func (v *viewPane) Focus(delegate func(p tview.Primitive)) {
+	v.TextView.Focus(delegate)
+	if v.structured != nil {
+		v.structured.Focus(func(tview.Primitive) {})
+	}
+}
+
+func (v *viewPane) Blur() {
+	v.TextView.Blur()
+	if v.structured != nil {
+		v.structured.Blur()
+	}
+}
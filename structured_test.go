@@ -0,0 +1,147 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+func TestParseJSON(t *testing.T) {
+	useTheme(t, "monochrome")
+	cases := []struct {
+		text   string
+		labels []string
+	}{
+		{text: `{"b": 1, "a": [true, null, "x"]}`, labels: []string{"{…} (2 keys)"}},
+		{text: "{\"a\": 1}\n{\"a\": 2}\n", labels: []string{"{…} (1 keys)", "{…} (1 keys)"}},
+		{text: `[{"a": "[red]"}]`, labels: []string{"[…] (1 items)"}},
+		{text: `"foo"`},
+		{text: `{"a": 1`},
+		{text: "foo bar"},
+	}
+	for _, tc := range cases {
+		values, _ := parseJSON([]byte(tc.text))
+		var labels []string
+		for _, v := range values {
+			labels = append(labels, v.label())
+		}
+		if !reflect.DeepEqual(labels, tc.labels) {
+			t.Errorf("\nresult:   %q\nexpected: %q", labels, tc.labels)
+		}
+	}
+
+	values, _ := parseJSON([]byte(`{"b": 1, "a": [true, null, "x[red]"]}`))
+	var labels []string
+	for _, c := range append(values[0].children, values[0].children[1].children...) {
+		labels = append(labels, c.label())
+	}
+	expected := []string{"b: 1", "a: […] (3 items)", "0: true", "1: null", `2: "x[red[]"`}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("\nresult:   %q\nexpected: %q", labels, expected)
+	}
+}
+
+func TestParseTable(t *testing.T) {
+	cases := []struct {
+		text string
+		rows [][]string
+		kind string
+	}{
+		{
+			text: "a,b\n1,\"2,3\"",
+			rows: [][]string{{"a", "b"}, {"1", "2,3"}},
+			kind: kindCSV,
+		},
+		{
+			text: "a\tb c\n1\t2",
+			rows: [][]string{{"a", "b c"}, {"1", "2"}},
+			kind: kindTSV,
+		},
+		{
+			text: "PID TTY  CMD\n  1 ?    init splash\n 42 pts/0 sh",
+			rows: [][]string{{"PID", "TTY", "CMD"}, {"1", "?", "init splash"}, {"42", "pts/0", "sh"}},
+			kind: kindColumns,
+		},
+		{
+			text: "a b\n1 2\n",
+			rows: [][]string{{"a", "b"}, {"1", "2"}},
+			kind: kindColumns,
+		},
+		{
+			text: "a,b\n1,2,3",
+		},
+		{
+			text: "foo bar\nbaz",
+		},
+		{
+			text: "foo\nbar",
+		},
+	}
+	for _, tc := range cases {
+		rows, kind := parseTable([]byte(tc.text))
+		if !reflect.DeepEqual(rows, tc.rows) || kind != tc.kind {
+			t.Errorf("\nresult:   %q %s\nexpected: %q %s", rows, kind, tc.rows, tc.kind)
+		}
+	}
+}
+
+func TestStructuredView(t *testing.T) {
+	useTheme(t, "monochrome")
+	getTerminalHeight = func() int {
+		return 6
+	}
+	screen := tcell.NewSimulationScreen("")
+	screen.Init()
+	screen.SetSize(30, 6)
+
+	v := newViewPane("stdout/stderr")
+	v.SetRect(0, 0, 30, 6)
+	v.setContent([]byte("name,age\nfoo,20\n"))
+	if v.structured != nil || v.title() != "stdout/stderr" {
+		t.Errorf("result: %s", v.title())
+	}
+
+	v.toggleStructured()
+	if v.title() != "stdout/stderr (csv)" {
+		t.Errorf("result: %s", v.title())
+	}
+	v.Draw(screen)
+	screen.Show()
+	expected := []string{"1   │2", "name│age", "foo │20"}
+	for i, line := range expected {
+		if result := screenLine(screen, i+1); result != line {
+			t.Errorf("\nresult:   %q\nexpected: %q", result, line)
+		}
+	}
+
+	v.setContent([]byte("foo\n"))
+	if v.structured != nil || v.title() != "stdout/stderr (no structure)" {
+		t.Errorf("result: %s", v.title())
+	}
+	v.toggleStructured()
+	if v.title() != "stdout/stderr" {
+		t.Errorf("result: %s", v.title())
+	}
+}
+
+func TestStructuredViewLimit(t *testing.T) {
+	// Only the lines within the limit are shown as a table.
+	kind, view := newStructuredView([]byte("PID CMD\n1 init\n2 kthreadd\n3 rcu_gp\n"), 2)
+	table, ok := view.(*tview.Table)
+	if kind != kindColumns || !ok || table.GetRowCount() != 3 {
+		t.Errorf("result: %s %T", kind, view)
+	}
+}
+
+// screenLine returns the text inside the border in row y of screen.
+func screenLine(screen tcell.SimulationScreen, y int) string {
+	cells, width, _ := screen.GetContents()
+	var b strings.Builder
+	for x := 1; x < width-1; x++ {
+		b.WriteString(string(cells[y*width+x].Runes))
+	}
+	return strings.TrimRight(b.String(), " ")
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// theme holds the colors and the prompt symbols of the UI. Colors are tcell
+// color names or #rrggbb values. An empty value or "default" uses the
+// terminal's color, and an empty symbol the default one.
+type theme struct {
+	Base        string `json:"base"`
+	Background  string `json:"background"`
+	Text        string `json:"text"`
+	Input       string `json:"input"`
+	Prompt      string `json:"prompt"`
+	Border      string `json:"border"`
+	BorderFocus string `json:"border_focus"`
+	Title       string `json:"title"`
+	Error       string `json:"error"`
+	Spinner     string `json:"spinner"`
+	Selection   string `json:"selection"`
+	Deleted     string `json:"deleted"`
+	Inserted    string `json:"inserted"`
+
+	PipeSymbol    string `json:"pipe_symbol"`
+	NoInputSymbol string `json:"no_input_symbol"`
+}
+
+var themes = map[string]theme{
+	"dark": {
+		Background:  "black",
+		Text:        "white",
+		Input:       "blue",
+		Prompt:      "yellow",
+		Border:      "white",
+		BorderFocus: "aqua",
+		Title:       "white",
+		Error:       "red",
+		Spinner:     "aqua",
+		Selection:   "yellow",
+		Deleted:     "red",
+		Inserted:    "green",
+	},
+	"light": {
+		Background:  "white",
+		Text:        "black",
+		Input:       "silver",
+		Prompt:      "navy",
+		Border:      "gray",
+		BorderFocus: "blue",
+		Title:       "black",
+		Error:       "maroon",
+		Spinner:     "blue",
+		Selection:   "olive",
+		Deleted:     "maroon",
+		Inserted:    "green",
+	},
+	"monochrome": {},
+}
+
+var currentTheme = themes["dark"]
+
+// loadTheme resolves name against the built-in themes and the user-defined
+// themes of the config. A user-defined theme inherits the colors it doesn't
+// set from its base theme (dark by default).
+func loadTheme(name string, userThemes map[string]json.RawMessage) (theme, error) {
+	raw, ok := userThemes[name]
+	if !ok {
+		th, ok := themes[name]
+		if !ok {
+			return theme{}, fmt.Errorf("unknown theme %q", name)
+		}
+		return th, nil
+	}
+
+	var base theme
+	if err := json.Unmarshal(raw, &base); err != nil {
+		return theme{}, fmt.Errorf("theme %q: %w", name, err)
+	}
+	if base.Base == "" {
+		base.Base = "dark"
+	}
+	th, ok := themes[base.Base]
+	if !ok {
+		return theme{}, fmt.Errorf("theme %q: unknown base theme %q", name, base.Base)
+	}
+	if err := json.Unmarshal(raw, &th); err != nil {
+		return theme{}, fmt.Errorf("theme %q: %w", name, err)
+	}
+	for _, c := range []string{th.Background, th.Text, th.Input, th.Prompt, th.Border, th.BorderFocus, th.Title, th.Error, th.Spinner, th.Selection, th.Deleted, th.Inserted} {
+		if c != "" && c != "default" && tcell.GetColor(c) == tcell.ColorDefault {
+			return theme{}, fmt.Errorf("theme %q: unknown color %q", name, c)
+		}
+	}
+	return th, nil
+}
+
+// noColor reports whether colors are disabled by NO_COLOR (https://no-color.org).
+func noColor() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+func color(name string) tcell.Color {
+	return tcell.GetColor(name)
+}
+
+// tag returns a tview color tag for name, or "" for the default color.
+func tag(name string) string {
+	if name == "" || name == "default" {
+		return ""
+	}
+	return "[" + name + "]"
+}
+
+// apply sets the default styles of tview. It must be called before any
+// primitive is created.
+func (th theme) apply() {
+	tview.Styles.PrimitiveBackgroundColor = color(th.Background)
+	tview.Styles.ContrastBackgroundColor = color(th.Input)
+	tview.Styles.PrimaryTextColor = color(th.Text)
+	tview.Styles.SecondaryTextColor = color(th.Prompt)
+	tview.Styles.BorderColor = color(th.Border)
+	tview.Styles.TitleColor = color(th.Title)
+}
+
+func (th theme) errorTag() string {
+	if t := tag(th.Error); t != "" {
+		return t
+	}
+	return "[::b]"
+}
+
+func (th theme) spinnerTag() string {
+	return tag(th.Spinner)
+}
+
+// promptSymbol returns the symbol before the command line, which shows
+// whether there is input to pipe into the command.
+func (th theme) promptSymbol(input bool) string {
+	symbol, fallback := th.PipeSymbol, "| "
+	if !input {
+		symbol, fallback = th.NoInputSymbol, "> "
+	}
+	if symbol == "" {
+		return fallback
+	}
+	return symbol
+}
+
+// setFocusBorder colors the border of v while it has focus.
+func (th theme) setFocusBorder(v *viewPane) {
+	v.SetFocusFunc(func() {
+		v.SetBorderColor(color(th.BorderFocus))
+	})
+	v.SetBlurFunc(func() {
+		v.SetBorderColor(color(th.Border))
+	})
+}
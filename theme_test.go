@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// useTheme sets the current theme for the test.
+func useTheme(t *testing.T, name string) {
+	t.Helper()
+	prev := currentTheme
+	currentTheme = themes[name]
+	t.Cleanup(func() {
+		currentTheme = prev
+	})
+}
+
+func TestLoadTheme(t *testing.T) {
+	userThemes := map[string]json.RawMessage{
+		"solarized": json.RawMessage(`{"prompt": "#b58900", "border": "#586e75"}`),
+		"paper":     json.RawMessage(`{"base": "light", "error": "red"}`),
+		"broken":    json.RawMessage(`{"prompt": "no-such-color"}`),
+		"orphan":    json.RawMessage(`{"base": "no-such-theme"}`),
+	}
+
+	cases := []struct {
+		name    string
+		prompt  string
+		border  string
+		error   string
+		isError bool
+	}{
+		{name: "dark", prompt: "yellow", border: "white", error: "red", isError: false},
+		{name: "monochrome", prompt: "", border: "", error: "", isError: false},
+		{name: "solarized", prompt: "#b58900", border: "#586e75", error: "red", isError: false},
+		{name: "paper", prompt: "navy", border: "gray", error: "red", isError: false},
+		{name: "broken", isError: true},
+		{name: "orphan", isError: true},
+		{name: "unknown", isError: true},
+	}
+	for _, tc := range cases {
+		th, err := loadTheme(tc.name, userThemes)
+		if (err != nil) != tc.isError {
+			t.Errorf("name: %s, error: %v", tc.name, err)
+		}
+		if err != nil {
+			continue
+		}
+		if th.Prompt != tc.prompt || th.Border != tc.border || th.Error != tc.error {
+			t.Errorf("name: %s, result: %+v", tc.name, th)
+		}
+	}
+}
+
+func TestPromptSymbol(t *testing.T) {
+	userThemes := map[string]json.RawMessage{
+		"arrows": json.RawMessage(`{"pipe_symbol": "❯ ", "no_input_symbol": "∅ "}`),
+	}
+	cases := []struct {
+		name   string
+		input  bool
+		result string
+	}{
+		{name: "dark", input: true, result: "| "},
+		{name: "dark", input: false, result: "> "},
+		{name: "arrows", input: true, result: "❯ "},
+		{name: "arrows", input: false, result: "∅ "},
+	}
+	for _, tc := range cases {
+		th, err := loadTheme(tc.name, userThemes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result := th.promptSymbol(tc.input); result != tc.result {
+			t.Errorf("\nresult:   %q\nexpected: %q", result, tc.result)
+		}
+	}
+
+	// The command line shows the symbol of the current theme.
+	th, _ := loadTheme("arrows", userThemes)
+	prev := currentTheme
+	currentTheme = th
+	t.Cleanup(func() {
+		currentTheme = prev
+	})
+	c := newCliPane()
+	if result := c.GetLabel(); result != "∅ " {
+		t.Errorf("\nresult:   %q\nexpected: %q", result, "∅ ")
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// editState is a snapshot of the pipeline being edited in the cliPane.
+type editState struct {
+	prompt string
+	text   string
+	cursor int
+}
+
+func (c *cliPane) state() editState {
+	return editState{
+		prompt: c.prompt,
+		text:   c.GetText(),
+		cursor: c.cursor(),
+	}
+}
+
+// record pushes the state before the latest change onto the undo stack.
+// Consecutive typed characters are merged into a single undo step.
+func (c *cliPane) record() {
+	if c.restoring {
+		return
+	}
+	s := c.state()
+	if s.prompt == c.last.prompt && s.text == c.last.text {
+		return
+	}
+	if !c.typing || !c.lastTyping || s.prompt != c.last.prompt {
+		c.undoStack = append(c.undoStack, c.last)
+	}
+	c.redoStack = nil
+	c.last = s
+	c.lastTyping = c.typing
+}
+
+// recordChange records a change of the text. While a key is handled, the
+// input field holds its lock and the cursor can't be probed, so the change
+// is recorded once the key has been handled.
+func (c *cliPane) recordChange() {
+	if c.handling {
+		c.changed = true
+		return
+	}
+	c.record()
+}
+
+// handle runs a handler of the input field and records the change it made
+// afterwards.
+func (c *cliPane) handle(handler func()) {
+	c.handling = true
+	handler()
+	c.handling = false
+	if c.changed {
+		c.changed = false
+		c.record()
+	}
+}
+
+func (c *cliPane) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	handler := c.InputField.InputHandler()
+	return func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		c.handle(func() { handler(event, setFocus) })
+	}
+}
+
+func (c *cliPane) PasteHandler() func(text string, setFocus func(p tview.Primitive)) {
+	handler := c.InputField.PasteHandler()
+	return func(text string, setFocus func(p tview.Primitive)) {
+		c.handle(func() { handler(text, setFocus) })
+	}
+}
+
+func (c *cliPane) MouseHandler() func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (bool, tview.Primitive) {
+	handler := c.InputField.MouseHandler()
+	return func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (consumed bool, capture tview.Primitive) {
+		c.handle(func() { consumed, capture = handler(action, event, setFocus) })
+		return
+	}
+}
+
+func (c *cliPane) undo() {
+	if len(c.undoStack) == 0 {
+		return
+	}
+	s := c.undoStack[len(c.undoStack)-1]
+	c.undoStack = c.undoStack[:len(c.undoStack)-1]
+	c.redoStack = append(c.redoStack, c.state())
+	c.restore(s)
+}
+
+func (c *cliPane) redo() {
+	if len(c.redoStack) == 0 {
+		return
+	}
+	s := c.redoStack[len(c.redoStack)-1]
+	c.redoStack = c.redoStack[:len(c.redoStack)-1]
+	c.undoStack = append(c.undoStack, c.state())
+	c.restore(s)
+}
+
+func (c *cliPane) restore(s editState) {
+	c.restoring = true
+	defer func() {
+		c.restoring = false
+	}()
+
+	c.prompt = s.prompt
+	c.updateLabel()
+	c.SetText(s.text)
+	c.setCursor(s.cursor)
+	c.last = s
+	c.lastTyping = false
+}
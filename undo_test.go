@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+func TestUndo(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	screen.Init()
+	defer screen.Fini()
+
+	c := newCliPane()
+	c.SetRect(0, 0, 80, 1)
+	c.Draw(screen)
+	c.SetChangedFunc(func(text string) {
+		c.record()
+	})
+
+	c.typing = true
+	c.SetText("g")
+	c.SetText("gr")
+	c.SetText("gre")
+	c.typing = false
+	c.SetText("")
+	c.SetText("grep a ")
+	c.addPrompt()
+	c.record()
+	c.SetText(" wc")
+
+	cases := []struct {
+		undo   bool
+		prompt string
+		text   string
+	}{
+		{undo: true, prompt: "grep a ", text: ""},
+		{undo: true, prompt: "", text: "grep a "},
+		{undo: true, prompt: "", text: ""},
+		{undo: true, prompt: "", text: "gre"},
+		{undo: true, prompt: "", text: ""},
+		{undo: true, prompt: "", text: ""},
+		{undo: false, prompt: "", text: "gre"},
+		{undo: false, prompt: "", text: ""},
+		{undo: false, prompt: "", text: "grep a "},
+		{undo: false, prompt: "grep a ", text: ""},
+		{undo: false, prompt: "grep a ", text: " wc"},
+		{undo: false, prompt: "grep a ", text: " wc"},
+	}
+	for i, tc := range cases {
+		if tc.undo {
+			c.undo()
+		} else {
+			c.redo()
+		}
+		if c.prompt != tc.prompt || c.GetText() != tc.text {
+			t.Errorf("step %d\nresult:   %q %q\nexpected: %q %q", i, c.prompt, c.GetText(), tc.prompt, tc.text)
+		}
+	}
+}
+
+func TestUndoKeyEvent(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	screen.Init()
+	defer screen.Fini()
+
+	c := newCliPane()
+	c.SetRect(0, 0, 80, 1)
+	c.Draw(screen)
+	c.SetChangedFunc(func(text string) {
+		c.recordChange()
+	})
+
+	c.typing = true
+	done := make(chan struct{})
+	go func() {
+		handler := c.InputHandler()
+		handler(tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone), func(p tview.Primitive) {})
+		handler(tcell.NewEventKey(tcell.KeyRune, 'b', tcell.ModNone), func(p tview.Primitive) {})
+		c.PasteHandler()("c", func(p tview.Primitive) {})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("key event deadlocked")
+	}
+
+	if s := c.state(); s.text != "abc" || s.cursor != 3 {
+		t.Errorf("\nresult:   %q %d\nexpected: %q %d", s.text, s.cursor, "abc", 3)
+	}
+	c.undo()
+	c.undo()
+	if c.GetText() != "" {
+		t.Errorf("\nresult:   %q\nexpected: %q", c.GetText(), "")
+	}
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+type viMode int
+
+const (
+	viInsert viMode = iota
+	viNormal
+)
+
+// viEditor is a modal vi editing layer for the cliPane.
+// In insert mode keys are handled by the input field as usual,
+// in normal mode they are interpreted as vi commands against text and cursor.
+type viEditor struct {
+	mode     viMode
+	text     []rune
+	cursor   int
+	pending  []rune
+	register []rune
+	lastFind []rune
+}
+
+func newViEditor() *viEditor {
+	return &viEditor{
+		mode: viInsert,
+	}
+}
+
+func (v *viEditor) indicator() string {
+	if v.mode == viNormal {
+		return "[N]"
+	}
+	return "[I]"
+}
+
+// enterNormal switches to normal mode with the text and the cursor position
+// taken over from the input field.
+func (v *viEditor) enterNormal(text string, cursor int) {
+	v.mode = viNormal
+	v.text = []rune(text)
+	v.cursor = cursor - 1
+	v.pending = nil
+	v.clamp()
+}
+
+func (v *viEditor) enterInsert(cursor int) {
+	v.mode = viInsert
+	v.cursor = min(max(cursor, 0), len(v.text))
+}
+
+func (v *viEditor) clamp() {
+	v.cursor = min(v.cursor, len(v.text)-1)
+	v.cursor = max(v.cursor, 0)
+}
+
+func (v *viEditor) handleKey(event *tcell.EventKey) {
+	switch event.Key() {
+	case tcell.KeyRune:
+		v.key(event.Rune())
+	case tcell.KeyEscape:
+		v.pending = nil
+	case tcell.KeyLeft, tcell.KeyBackspace, tcell.KeyBackspace2:
+		v.key('h')
+	case tcell.KeyRight:
+		v.key('l')
+	case tcell.KeyHome:
+		v.key('0')
+	case tcell.KeyEnd:
+		v.key('$')
+	case tcell.KeyDelete:
+		v.key('x')
+	}
+}
+
+func (v *viEditor) key(r rune) {
+	keys := append(v.pending, r)
+	if v.execute(keys) {
+		v.pending = nil
+		return
+	}
+	v.pending = keys
+}
+
+// execute runs a complete normal mode command.
+// It returns false if more keys are needed to complete the command.
+func (v *viEditor) execute(keys []rune) bool {
+	switch keys[0] {
+	case 'd', 'c', 'y':
+		if len(keys) == 1 {
+			return false
+		}
+		return v.operate(keys[0], keys[1:])
+	case 'r':
+		if len(keys) == 1 {
+			return false
+		}
+		if v.cursor < len(v.text) {
+			v.text[v.cursor] = keys[1]
+		}
+		return true
+	case 'i':
+		v.enterInsert(v.cursor)
+	case 'a':
+		v.enterInsert(v.cursor + 1)
+	case 'I':
+		v.enterInsert(v.firstNonBlank())
+	case 'A':
+		v.enterInsert(len(v.text))
+	case 'x':
+		v.operate('d', []rune{'l'})
+	case 'X':
+		v.operate('d', []rune{'h'})
+	case 's':
+		v.operate('c', []rune{'l'})
+	case 'S':
+		v.operate('c', []rune{'c'})
+	case 'D':
+		v.operate('d', []rune{'$'})
+	case 'C':
+		v.operate('c', []rune{'$'})
+	case 'p':
+		v.put(min(v.cursor+1, len(v.text)))
+	case 'P':
+		v.put(v.cursor)
+	case '~':
+		if v.cursor < len(v.text) {
+			r := v.text[v.cursor]
+			if unicode.IsUpper(r) {
+				v.text[v.cursor] = unicode.ToLower(r)
+			} else {
+				v.text[v.cursor] = unicode.ToUpper(r)
+			}
+			v.cursor++
+			v.clamp()
+		}
+	default:
+		pos, _, ok, complete := v.motion(keys)
+		if !complete {
+			return false
+		}
+		if ok {
+			v.cursor = pos
+			v.clamp()
+		}
+	}
+	return true
+}
+
+func (v *viEditor) put(pos int) {
+	if len(v.register) == 0 {
+		return
+	}
+	text := make([]rune, 0, len(v.text)+len(v.register))
+	text = append(text, v.text[:pos]...)
+	text = append(text, v.register...)
+	text = append(text, v.text[pos:]...)
+	v.text = text
+	v.cursor = pos + len(v.register) - 1
+	v.clamp()
+}
+
+// operate applies the operator op (d, c or y) to the range described by
+// the motion or text object in keys.
+func (v *viEditor) operate(op rune, keys []rune) bool {
+	start, end := 0, len(v.text)
+	switch {
+	case keys[0] == op:
+	case keys[0] == 'i' || keys[0] == 'a':
+		if len(keys) == 1 {
+			return false
+		}
+		var ok bool
+		start, end, ok = v.textObject(keys[0], keys[1])
+		if !ok {
+			return true
+		}
+	default:
+		// cw and cW behave like ce and cE when the cursor is on a word.
+		if op == 'c' && (keys[0] == 'w' || keys[0] == 'W') && v.cursor < len(v.text) && !isBlank(v.text[v.cursor]) {
+			keys = []rune{keys[0] - 'w' + 'e'}
+		}
+		pos, inclusive, ok, complete := v.motion(keys)
+		if !complete {
+			return false
+		}
+		if !ok {
+			return true
+		}
+		start, end = v.cursor, max(pos, 0)
+		if end < start {
+			start, end = end, start
+		} else if inclusive {
+			end++
+		}
+		end = min(end, len(v.text))
+	}
+
+	v.register = append([]rune(nil), v.text[start:end]...)
+	switch op {
+	case 'y':
+		v.cursor = start
+	case 'd':
+		v.text = append(v.text[:start:start], v.text[end:]...)
+		v.cursor = start
+		v.clamp()
+	case 'c':
+		v.text = append(v.text[:start:start], v.text[end:]...)
+		v.mode = viInsert
+		v.cursor = start
+	}
+	return true
+}
+
+// motion returns the target position of the motion in keys and whether
+// the character at the target is included by operators.
+// ok is false if the motion failed, complete is false if more keys are needed.
+func (v *viEditor) motion(keys []rune) (pos int, inclusive, ok, complete bool) {
+	pos = v.cursor
+	switch keys[0] {
+	case 'h':
+		pos = max(v.cursor-1, 0)
+	case 'l', ' ':
+		pos = min(v.cursor+1, len(v.text))
+	case '0', '|':
+		pos = 0
+	case '^':
+		pos = v.firstNonBlank()
+	case '$':
+		pos, inclusive = len(v.text)-1, true
+	case 'w', 'W':
+		pos = v.wordForward(keys[0] == 'W')
+	case 'b', 'B':
+		pos = v.wordBackward(keys[0] == 'B')
+	case 'e', 'E':
+		pos, inclusive = v.wordEnd(keys[0] == 'E'), true
+	case 'f', 't', 'F', 'T':
+		if len(keys) == 1 {
+			return pos, false, false, false
+		}
+		v.lastFind = []rune{keys[0], keys[1]}
+		return v.find(keys[0], keys[1])
+	case ';', ',':
+		if v.lastFind == nil {
+			return pos, false, false, true
+		}
+		cmd := v.lastFind[0]
+		if keys[0] == ',' {
+			cmd = reverseFind(cmd)
+		}
+		return v.find(cmd, v.lastFind[1])
+	default:
+		return pos, false, false, true
+	}
+	return pos, inclusive, true, true
+}
+
+func reverseFind(cmd rune) rune {
+	switch cmd {
+	case 'f':
+		return 'F'
+	case 'F':
+		return 'f'
+	case 't':
+		return 'T'
+	default:
+		return 't'
+	}
+}
+
+func (v *viEditor) find(cmd, ch rune) (pos int, inclusive, ok, complete bool) {
+	switch cmd {
+	case 'f', 't':
+		for i := v.cursor + 1; i < len(v.text); i++ {
+			if v.text[i] != ch {
+				continue
+			}
+			if cmd == 't' {
+				i--
+			}
+			return i, true, true, true
+		}
+	case 'F', 'T':
+		for i := v.cursor - 1; i >= 0; i-- {
+			if v.text[i] != ch {
+				continue
+			}
+			if cmd == 'T' {
+				i++
+			}
+			return i, false, true, true
+		}
+	}
+	return v.cursor, false, false, true
+}
+
+func (v *viEditor) textObject(kind, object rune) (start, end int, ok bool) {
+	if len(v.text) == 0 || (object != 'w' && object != 'W') {
+		return 0, 0, false
+	}
+	big := object == 'W'
+	c := min(v.cursor, len(v.text)-1)
+	class := charClass(v.text[c], big)
+	start, end = c, c+1
+	for start > 0 && charClass(v.text[start-1], big) == class {
+		start--
+	}
+	for end < len(v.text) && charClass(v.text[end], big) == class {
+		end++
+	}
+	if kind == 'a' && class != 0 {
+		e := end
+		for e < len(v.text) && isBlank(v.text[e]) {
+			e++
+		}
+		if e > end {
+			end = e
+		} else {
+			for start > 0 && isBlank(v.text[start-1]) {
+				start--
+			}
+		}
+	}
+	return start, end, true
+}
+
+func (v *viEditor) firstNonBlank() int {
+	for i, r := range v.text {
+		if !isBlank(r) {
+			return i
+		}
+	}
+	return 0
+}
+
+func (v *viEditor) wordForward(big bool) int {
+	i := v.cursor
+	if i >= len(v.text) {
+		return len(v.text)
+	}
+	if class := charClass(v.text[i], big); class != 0 {
+		for i < len(v.text) && charClass(v.text[i], big) == class {
+			i++
+		}
+	}
+	for i < len(v.text) && isBlank(v.text[i]) {
+		i++
+	}
+	return i
+}
+
+func (v *viEditor) wordEnd(big bool) int {
+	i := v.cursor + 1
+	for i < len(v.text) && isBlank(v.text[i]) {
+		i++
+	}
+	if i >= len(v.text) {
+		return max(len(v.text)-1, 0)
+	}
+	class := charClass(v.text[i], big)
+	for i+1 < len(v.text) && charClass(v.text[i+1], big) == class {
+		i++
+	}
+	return i
+}
+
+func (v *viEditor) wordBackward(big bool) int {
+	i := min(v.cursor, len(v.text)) - 1
+	for i > 0 && isBlank(v.text[i]) {
+		i--
+	}
+	if i <= 0 {
+		return 0
+	}
+	class := charClass(v.text[i], big)
+	for i > 0 && charClass(v.text[i-1], big) == class {
+		i--
+	}
+	return i
+}
+
+func isBlank(r rune) bool {
+	return r == ' ' || r == '\t'
+}
+
+// charClass classifies r as blank (0), keyword (1) or punctuation (2).
+// With big, every non-blank character belongs to the same class.
+func charClass(r rune, big bool) int {
+	switch {
+	case isBlank(r):
+		return 0
+	case big || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// handleVi interprets event as a vi command when the cliPane is in normal
+// mode and returns nil if the event was consumed.
+func (c *cliPane) handleVi(event *tcell.EventKey) *tcell.EventKey {
+	v := c.vi
+	if v.mode == viInsert {
+		if event.Key() != tcell.KeyEscape {
+			return event
+		}
+		v.enterNormal(c.GetText(), c.cursor())
+		c.updateLabel()
+		c.setCursor(v.cursor)
+		return nil
+	}
+
+	switch {
+	case event.Key() == tcell.KeyEnter, event.Key() == tcell.KeyCtrlC:
+		return event
+	case event.Key() == tcell.KeyCtrlR && len(v.pending) == 0:
+		return tcell.NewEventKey(tcell.KeyCtrlY, 0, tcell.ModNone)
+	case event.Key() == tcell.KeyRune && event.Rune() == 'u' && len(v.pending) == 0:
+		return tcell.NewEventKey(tcell.KeyCtrlZ, 0, tcell.ModNone)
+	case event.Key() == tcell.KeyRune && event.Rune() == 'v' && len(v.pending) == 0:
+		return tcell.NewEventKey(tcell.KeyCtrlO, 0, tcell.ModNone)
+	}
+	if text := c.GetText(); text != string(v.text) {
+		v.text = []rune(text)
+		v.cursor = c.cursor()
+		v.clamp()
+	}
+	v.handleKey(event)
+	if string(v.text) != c.GetText() {
+		c.SetText(string(v.text))
+	}
+	c.updateLabel()
+	c.setCursor(v.cursor)
+	return nil
+}
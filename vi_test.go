@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestViEditor(t *testing.T) {
+	cases := []struct {
+		text   string
+		cursor int
+		keys   string
+		result string
+		pos    int
+		mode   viMode
+	}{
+		{text: "grep foo bar", cursor: 0, keys: "w", result: "grep foo bar", pos: 5, mode: viNormal},
+		{text: "grep foo bar", cursor: 12, keys: "b", result: "grep foo bar", pos: 9, mode: viNormal},
+		{text: "grep foo bar", cursor: 0, keys: "e", result: "grep foo bar", pos: 3, mode: viNormal},
+		{text: "grep foo bar", cursor: 0, keys: "$", result: "grep foo bar", pos: 11, mode: viNormal},
+		{text: "grep foo bar", cursor: 0, keys: "dw", result: "foo bar", pos: 0, mode: viNormal},
+		{text: "grep foo bar", cursor: 7, keys: "ciw", result: "grep  bar", pos: 5, mode: viInsert},
+		{text: "grep foo bar", cursor: 7, keys: "daw", result: "grep bar", pos: 5, mode: viNormal},
+		{text: "grep foo bar", cursor: 2, keys: "cw", result: "g foo bar", pos: 1, mode: viInsert},
+		{text: "awk '{print $1}'", cursor: 0, keys: "f$", result: "awk '{print $1}'", pos: 12, mode: viNormal},
+		{text: "awk '{print $1}'", cursor: 0, keys: "dt{", result: "{print $1}'", pos: 0, mode: viNormal},
+		{text: "awk '{print $1}'", cursor: 0, keys: "df'", result: "{print $1}'", pos: 0, mode: viNormal},
+		{text: "a,b,c", cursor: 0, keys: "f,;", result: "a,b,c", pos: 3, mode: viNormal},
+		{text: "sort -n", cursor: 7, keys: "D", result: "sort -", pos: 5, mode: viNormal},
+		{text: "sort -n", cursor: 0, keys: "ywP", result: "sort sort -n", pos: 4, mode: viNormal},
+		{text: "sort -n", cursor: 0, keys: "rSl~", result: "SOrt -n", pos: 2, mode: viNormal},
+		{text: "sort -n", cursor: 0, keys: "A", result: "sort -n", pos: 7, mode: viInsert},
+	}
+	for _, tc := range cases {
+		v := newViEditor()
+		v.enterNormal(tc.text, tc.cursor)
+		for _, r := range tc.keys {
+			v.key(r)
+		}
+		result := fmt.Sprintf("%q %d %d", string(v.text), v.cursor, v.mode)
+		expected := fmt.Sprintf("%q %d %d", tc.result, tc.pos, tc.mode)
+		if result != expected {
+			t.Errorf("keys: %q\nresult:   %s\nexpected: %s", tc.keys, result, expected)
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	cases := []struct {
+		text   string
+		cursor int
+		result int
+	}{
+		{text: "grep a", cursor: 0, result: 0},
+		{text: "grep a", cursor: 3, result: 3},
+		{text: "grep a", cursor: 10, result: 6},
+		{text: "grep あい", cursor: 6, result: 6},
+	}
+	screen := tcell.NewSimulationScreen("")
+	screen.Init()
+	defer screen.Fini()
+
+	for _, tc := range cases {
+		c := newCliPane()
+		c.SetRect(0, 0, 80, 1)
+		c.Draw(screen)
+		c.SetText(tc.text)
+		c.setCursor(tc.cursor)
+		if c.cursor() != tc.result {
+			t.Errorf("result: %d, expected: %d", c.cursor(), tc.result)
+		}
+		if c.GetText() != tc.text {
+			t.Errorf("result: %q, expected: %q", c.GetText(), tc.text)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"golang.org/x/text/transform"
+)
+
+// visibleTransformer makes tabs, trailing spaces, CRs and NULs visible
+// like `cat -A`.
+type visibleTransformer struct {
+	spaces int
+}
+
+func (vt *visibleTransformer) Reset() {
+	vt.spaces = 0
+}
+
+func (vt *visibleTransformer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for ; nSrc < len(src); nSrc++ {
+		c := src[nSrc]
+		if c == ' ' {
+			vt.spaces++
+			continue
+		}
+
+		space := " "
+		if c == '\n' || c == '\r' {
+			space = "·"
+		}
+		n, ok := vt.flush(dst[nDst:], space)
+		nDst += n
+		if !ok {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+
+		out := src[nSrc : nSrc+1]
+		switch c {
+		case '\t':
+			out = []byte("→")
+		case '\r':
+			out = []byte("␍")
+		case 0:
+			out = []byte("␀")
+		}
+		if len(out) > len(dst)-nDst {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], out)
+	}
+
+	if atEOF {
+		n, ok := vt.flush(dst[nDst:], "·")
+		nDst += n
+		if !ok {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+	}
+	return nDst, nSrc, nil
+}
+
+// flush writes the pending spaces to dst as space.
+func (vt *visibleTransformer) flush(dst []byte, space string) (int, bool) {
+	n := 0
+	for ; vt.spaces > 0; vt.spaces-- {
+		if len(space) > len(dst)-n {
+			return n, false
+		}
+		n += copy(dst[n:], space)
+	}
+	return n, true
+}
+
+func (v *viewPane) toggleWhitespace() {
+	v.syncUpdate(func() {
+		v.whitespace = !v.whitespace
+	})
+}
+
+func (v *viewPane) whitespaceStatus() string {
+	if !v.whitespace {
+		return ""
+	}
+	return " (whitespace)"
+}
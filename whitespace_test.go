@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/transform"
+)
+
+func TestVisibleTransform(t *testing.T) {
+	cases := []struct {
+		input  string
+		result string
+	}{
+		{input: "a\tb\n", result: "a→b\n"},
+		{input: "a b  \n", result: "a b··\n"},
+		{input: "a \r\n", result: "a·␍\n"},
+		{input: "a\x00b", result: "a␀b"},
+		{input: "a  ", result: "a··"},
+		{input: strings.Repeat(" ", 5000) + "a", result: strings.Repeat(" ", 5000) + "a"},
+		{input: "\tあ \x1b[31m", result: "→あ \x1b[31m"},
+	}
+	for _, tc := range cases {
+		result, _, err := transform.String(&visibleTransformer{}, tc.input)
+		if err != nil || result != tc.result {
+			t.Errorf("\nresult:   %q %v\nexpected: %q", result, err, tc.result)
+		}
+	}
+}
+
+func TestWhitespace(t *testing.T) {
+	shell = "sh"
+	getTerminalHeight = func() int {
+		return 6
+	}
+
+	so := newStdoutViewPane()
+	so.toggleWhitespace()
+	so.execCommand(context.Background(), "printf 'a\\tb \\r\\n\\000'", storeBytes(nil))
+	if so.GetText(true) != "a→b·␍\n␀" || so.title() != "stdout/stderr (whitespace)" {
+		t.Errorf("result: %q %s", so.GetText(true), so.title())
+	}
+}
+
+func TestToggleWhitespaceWhileRunning(t *testing.T) {
+	tp, release := startPreview(t, "a b \n")
+	queueUpdate(t, tp, func() {
+		tp.redrawViewPane((*viewPane).toggleWhitespace)
+	})
+	release()
+	var result string
+	queueUpdate(t, tp, func() {
+		result = tp.stdoutPane.GetText(true)
+	})
+	if expected := "a b·\n"; result != expected {
+		t.Errorf("\nresult:   %q\nexpected: %q", result, expected)
+	}
+}